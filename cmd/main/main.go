@@ -1,20 +1,25 @@
 package main
 
 import (
-	"bufio"
 	"flag"
 	"os"
 
 	"panels_user_manager/pkg/cmd"
+	"panels_user_manager/pkg/importers"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/storage"
 	"panels_user_manager/pkg/utils"
 )
 
 func main() {
 	// Parse command-line flags
 	flag.BoolVar(&utils.VerboseMode, "v", false, "Enable verbose logging (use: -v)")
+	flag.BoolVar(&importers.ForceImport, "force", false, "Import files even if they do not match their integrity manifest")
+	flag.StringVar(&storage.IdentityFile, "identity", "", "age identity file used to decrypt encrypted export files")
+	flag.Func("reset-map", "Map 3X-UI reset periods to PasarGuard strategies, e.g. 15=month,3=week (others use the closest)", models.ParseResetStrategyOverrides)
 	flag.Parse()
 
-	reader := bufio.NewReader(os.Stdin)
+	reader := utils.Stdin
 	for {
 		cmd.ShowMenu()
 		choice, _ := reader.ReadString('\n')
@@ -28,6 +33,9 @@ func main() {
 			// PasarGuard Panel Operations
 			cmd.HandlePasarGuardMenu(reader)
 		case "3":
+			// Cross-panel tools
+			cmd.HandleToolsMenu(reader)
+		case "4":
 			os.Exit(0)
 		default:
 			println("Invalid option. Please try again.\n")
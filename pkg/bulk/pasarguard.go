@@ -0,0 +1,173 @@
+// Package bulk applies one change to many panel users at once: filters pick the users,
+// a preview lists them, and only then is anything sent to the panel.
+package bulk
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// UserFilter selects PasarGuard users. Unset fields match every user.
+type UserFilter struct {
+	Username     *regexp.Regexp
+	GroupID      int
+	Status       string  // active, disabled, limited, expired or on_hold
+	ExpiryFrom   int64   // unix time; with ExpiryTo, only users with a fixed expiry in the window match
+	ExpiryTo     int64   // unix time
+	UsageAbove   float64 // percent of the quota; unlimited users never match
+	NoteContains string  // case-insensitive
+}
+
+// Matches reports whether a user passes every set condition of the filter.
+func (f UserFilter) Matches(user models.PasarGuardUser) bool {
+	if f.Username != nil && !f.Username.MatchString(user.Username) {
+		return false
+	}
+	if f.GroupID != 0 && !containsInt(user.GroupIDs, f.GroupID) {
+		return false
+	}
+	if f.Status != "" && userStatus(user) != f.Status {
+		return false
+	}
+	if f.ExpiryFrom != 0 || f.ExpiryTo != 0 {
+		if user.ExpiryTime <= 0 || user.ExpiryTime < f.ExpiryFrom || user.ExpiryTime > f.ExpiryTo {
+			return false
+		}
+	}
+	if f.UsageAbove > 0 && (user.TotalGB <= 0 || float64(user.UsedTraffic)*100/float64(user.TotalGB) < f.UsageAbove) {
+		return false
+	}
+	if f.NoteContains != "" && !strings.Contains(strings.ToLower(user.Note), strings.ToLower(f.NoteContains)) {
+		return false
+	}
+	return true
+}
+
+// FilterUsers returns the users matching the filter.
+func FilterUsers(users []models.PasarGuardUser, filter UserFilter) []models.PasarGuardUser {
+	var matched []models.PasarGuardUser
+	for _, user := range users {
+		if filter.Matches(user) {
+			matched = append(matched, user)
+		}
+	}
+	return matched
+}
+
+// ListedUser names a user taken from a journal, export file or plain list. UUID may be empty.
+type ListedUser struct {
+	Username string
+	UUID     string
+}
+
+// MatchListed finds the listed users on the panel. Users listed with a UUID are matched on it
+// only, so users renamed on import (e.g. "john_1") are still found and a different user who later
+// took the name is never picked. Users without a UUID are matched by username. Listed users not
+// on the panel are returned as missing.
+func MatchListed(users []models.PasarGuardUser, listed []ListedUser) (matched []models.PasarGuardUser, missing []string) {
+	byUUID := make(map[string]int)
+	byName := make(map[string]int)
+	for i, user := range users {
+		if uuid := strings.ToLower(strings.TrimSpace(user.UUID)); uuid != "" {
+			byUUID[uuid] = i
+		}
+		byName[usernameKey(user.Username)] = i
+	}
+	seen := make(map[int]bool)
+	for _, l := range listed {
+		var i int
+		var found bool
+		if uuid := strings.ToLower(strings.TrimSpace(l.UUID)); uuid != "" {
+			i, found = byUUID[uuid]
+		} else {
+			i, found = byName[usernameKey(l.Username)]
+		}
+		if !found {
+			missing = append(missing, l.Username)
+			continue
+		}
+		if !seen[i] {
+			seen[i] = true
+			matched = append(matched, users[i])
+		}
+	}
+	return matched, missing
+}
+
+// usernameKey normalizes a username the way the importer does before creating users.
+func usernameKey(name string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+}
+
+// PrintUsers lists users before a bulk change is applied.
+func PrintUsers(users []models.PasarGuardUser) {
+	fmt.Printf("\n %-25s %-9s %21s %-17s %s\n", "USER", "STATUS", "USED / QUOTA", "EXPIRY", "GROUPS")
+	for _, user := range users {
+		fmt.Printf(" %-25s %-9s %21s %-17s %v\n", truncate(user.Username, 25), userStatus(user),
+			utils.FormatBytes(user.UsedTraffic)+" / "+formatQuota(user.TotalGB), formatExpiry(user.ExpiryTime, user.OnHoldExpireDuration), user.GroupIDs)
+	}
+	fmt.Printf(" "+utils.ColorDim+"%d user(s)"+utils.ColorReset+"\n", len(users))
+}
+
+// DeleteUsers removes the users from the panel and returns the errors by username.
+func DeleteUsers(client *clients.PasarGuardClient, users []models.PasarGuardUser) map[string]error {
+	failed := make(map[string]error)
+	for i, user := range users {
+		if err := client.DeleteUser(user.Username); err != nil {
+			failed[user.Username] = err
+			fmt.Printf(" "+utils.ColorRed+"[%d/%d] ✗ %s: %v"+utils.ColorReset+"\n", i+1, len(users), user.Username, err)
+			continue
+		}
+		fmt.Printf(" "+utils.ColorGreen+"[%d/%d] 🗑️ %s"+utils.ColorReset+"\n", i+1, len(users), user.Username)
+	}
+	return failed
+}
+
+// userStatus returns the status reported by the panel, or derives it for users read from files.
+func userStatus(user models.PasarGuardUser) string {
+	switch {
+	case user.Status != "":
+		return user.Status
+	case !user.Enable:
+		return "disabled"
+	case user.OnHoldExpireDuration > 0:
+		return "on_hold"
+	}
+	return "active"
+}
+
+func formatQuota(quota int64) string {
+	if quota <= 0 {
+		return "∞"
+	}
+	return utils.FormatBytes(quota)
+}
+
+func formatExpiry(expiry, onHold int64) string {
+	switch {
+	case onHold > 0:
+		return fmt.Sprintf("%dd after use", onHold/86400)
+	case expiry <= 0:
+		return "never"
+	}
+	return time.Unix(expiry, 0).Format("2006-01-02 15:04")
+}
+
+func truncate(s string, n int) string {
+	return s[:utils.Min(n, len(s))]
+}
+
+func containsInt(list []int, v int) bool {
+	for _, x := range list {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package bulk
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+const secondsPerDay = 86400
+
+// UserEdit is a change applied to every selected PasarGuard user. Zero fields change nothing.
+type UserEdit struct {
+	ExtendDays int   // added to fixed expiries and on-hold durations; negative values shorten them
+	FromToday  bool  // already expired users are extended from today instead of their old expiry
+	AddTraffic int64 // bytes added to finite quotas; negative values take traffic away
+	SetEnable  *bool
+}
+
+// Empty reports whether the edit would change nothing.
+func (e UserEdit) Empty() bool {
+	return e.ExtendDays == 0 && e.AddTraffic == 0 && e.SetEnable == nil
+}
+
+// Apply returns the user with the edit applied. Unlimited quotas and expiries stay unlimited.
+func (e UserEdit) Apply(user models.PasarGuardUser, now time.Time) models.PasarGuardUser {
+	if e.SetEnable != nil {
+		user.Enable = *e.SetEnable
+	}
+	if e.ExtendDays != 0 {
+		extend := int64(e.ExtendDays) * secondsPerDay
+		switch {
+		case user.OnHoldExpireDuration > 0:
+			user.OnHoldExpireDuration += extend
+			if user.OnHoldExpireDuration < secondsPerDay {
+				user.OnHoldExpireDuration = secondsPerDay
+			}
+		case user.ExpiryTime > 0:
+			base := user.ExpiryTime
+			if e.FromToday && base < now.Unix() {
+				base = now.Unix()
+			}
+			user.ExpiryTime = base + extend
+		}
+	}
+	if e.AddTraffic != 0 && user.TotalGB > 0 {
+		user.TotalGB += e.AddTraffic
+		if user.TotalGB < 1 {
+			user.TotalGB = 1 // 0 would mean unlimited
+		}
+	}
+	user.RemainingTraffic = 0
+	if user.TotalGB > user.UsedTraffic {
+		user.RemainingTraffic = user.TotalGB - user.UsedTraffic
+	}
+	return user
+}
+
+// UserChange is a planned edit of one user.
+type UserChange struct {
+	Before models.PasarGuardUser
+	After  models.PasarGuardUser
+}
+
+// PlanEdits applies the edit to the users without sending anything. Users the edit does not
+// change, e.g. unlimited ones when only quota or expiry is extended, are only counted.
+func PlanEdits(users []models.PasarGuardUser, edit UserEdit, now time.Time) (changes []UserChange, unaffected int) {
+	for _, user := range users {
+		after := edit.Apply(user, now)
+		if edit.SetEnable == nil && adminEnabled(user) {
+			// Limited and expired users were not disabled by an admin, so they are never sent
+			// as disabled. The panel re-checks their quota and expiry when they are updated.
+			after.Enable = true
+		}
+		c := UserChange{Before: user, After: after}
+		if len(c.diffs()) == 0 {
+			unaffected++
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes, unaffected
+}
+
+// diffs describes the fields the change modifies.
+func (c UserChange) diffs() []string {
+	var diffs []string
+	if b, a := formatExpiry(c.Before.ExpiryTime, c.Before.OnHoldExpireDuration), formatExpiry(c.After.ExpiryTime, c.After.OnHoldExpireDuration); b != a {
+		diffs = append(diffs, fmt.Sprintf("expiry %s → %s", b, a))
+	}
+	if c.Before.TotalGB != c.After.TotalGB {
+		diffs = append(diffs, fmt.Sprintf("quota %s → %s", formatQuota(c.Before.TotalGB), formatQuota(c.After.TotalGB)))
+	}
+	if adminEnabled(c.Before) != c.After.Enable {
+		diffs = append(diffs, fmt.Sprintf("status %s → %s", userStatus(c.Before), editedStatus(c.After)))
+	}
+	return diffs
+}
+
+// PrintChanges previews planned edits, one line per user with the fields that change.
+func PrintChanges(changes []UserChange, unaffected int) {
+	for _, c := range changes {
+		fmt.Printf(" "+utils.ColorYellow+"~ %-25s"+utils.ColorReset+" %s\n", truncate(c.Before.Username, 25), strings.Join(c.diffs(), ", "))
+	}
+	fmt.Printf(" "+utils.ColorDim+"%d user(s) to change, %d unaffected (unlimited quota or expiry, or already in that state)"+utils.ColorReset+"\n", len(changes), unaffected)
+}
+
+// ApplyChanges sends the planned edits to the panel and returns the errors by username.
+func ApplyChanges(client *clients.PasarGuardClient, changes []UserChange) map[string]error {
+	failed := make(map[string]error)
+	for i, c := range changes {
+		update := c.After
+		update.UsedTraffic = -1 // usage since the preview must not be overwritten
+		if err := client.UpdateUserByIdentifier(c.Before.Username, update); err != nil {
+			failed[c.Before.Username] = err
+			fmt.Printf(" "+utils.ColorRed+"[%d/%d] ✗ %s: %v"+utils.ColorReset+"\n", i+1, len(changes), c.Before.Username, err)
+			continue
+		}
+		fmt.Printf(" "+utils.ColorGreen+"[%d/%d] ✓ %s"+utils.ColorReset+"\n", i+1, len(changes), c.Before.Username)
+	}
+	return failed
+}
+
+// editedStatus is the status sent for an edited user, as UpdateUserByIdentifier derives it.
+func editedStatus(user models.PasarGuardUser) string {
+	switch {
+	case !user.Enable:
+		return "disabled"
+	case user.OnHoldExpireDuration > 0:
+		return "on_hold"
+	}
+	return "active"
+}
+
+// adminEnabled reports whether a user is enabled by its admin, i.e. not disabled. Limited and
+// expired users are enabled, they only ran out of traffic or time.
+func adminEnabled(user models.PasarGuardUser) bool {
+	return userStatus(user) != "disabled"
+}
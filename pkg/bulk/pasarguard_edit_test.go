@@ -0,0 +1,105 @@
+package bulk
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+)
+
+func TestPlanEdits(t *testing.T) {
+	const gb = 1 << 30
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := int64(secondsPerDay)
+	enable, disable := true, false
+
+	active := models.PasarGuardUser{Username: "active", Enable: true, Status: "active", TotalGB: 10 * gb, UsedTraffic: 2 * gb, ExpiryTime: now.Unix() + 5*day}
+	limited := models.PasarGuardUser{Username: "limited", Status: "limited", TotalGB: 10 * gb, UsedTraffic: 10 * gb, ExpiryTime: now.Unix() + 5*day}
+	limitedNoExpiry := models.PasarGuardUser{Username: "limited-no-expiry", Status: "limited", TotalGB: 10 * gb, UsedTraffic: 10 * gb}
+	expired := models.PasarGuardUser{Username: "expired", Status: "expired", TotalGB: 10 * gb, ExpiryTime: now.Unix() - 3*day}
+	disabled := models.PasarGuardUser{Username: "disabled", Status: "disabled", TotalGB: 10 * gb, ExpiryTime: now.Unix() + 5*day}
+	onHold := models.PasarGuardUser{Username: "on-hold", Enable: true, Status: "on_hold", OnHoldExpireDuration: 30 * day}
+	unlimited := models.PasarGuardUser{Username: "unlimited", Enable: true, Status: "active"}
+
+	tests := []struct {
+		name       string
+		user       models.PasarGuardUser
+		edit       UserEdit
+		unaffected bool
+		wantExpiry int64
+		wantHold   int64
+		wantTotal  int64
+		wantEnable bool
+	}{
+		{"extend active", active, UserEdit{ExtendDays: 10}, false, now.Unix() + 15*day, 0, 10 * gb, true},
+		{"shorten active", active, UserEdit{ExtendDays: -2}, false, now.Unix() + 3*day, 0, 10 * gb, true},
+		{"extend expired from old expiry", expired, UserEdit{ExtendDays: 10}, false, now.Unix() + 7*day, 0, 10 * gb, true},
+		{"extend expired from today", expired, UserEdit{ExtendDays: 10, FromToday: true}, false, now.Unix() + 10*day, 0, 10 * gb, true},
+		{"extend on hold", onHold, UserEdit{ExtendDays: 10}, false, 0, 40 * day, 0, true},
+		{"on hold never below a day", onHold, UserEdit{ExtendDays: -40}, false, 0, day, 0, true},
+		{"extend disabled stays disabled", disabled, UserEdit{ExtendDays: 10}, false, now.Unix() + 15*day, 0, 10 * gb, false},
+		{"add traffic to limited", limited, UserEdit{AddTraffic: 5 * gb}, false, now.Unix() + 5*day, 0, 15 * gb, true},
+		{"take traffic keeps a finite quota", active, UserEdit{AddTraffic: -20 * gb}, false, now.Unix() + 5*day, 0, 1, true},
+		{"extend limited without expiry", limitedNoExpiry, UserEdit{ExtendDays: 10}, true, 0, 0, 10 * gb, true},
+		{"enable limited is no change", limited, UserEdit{SetEnable: &enable}, true, 0, 0, 0, true},
+		{"disable limited", limited, UserEdit{SetEnable: &disable}, false, now.Unix() + 5*day, 0, 10 * gb, false},
+		{"enable disabled", disabled, UserEdit{SetEnable: &enable}, false, now.Unix() + 5*day, 0, 10 * gb, true},
+		{"extend unlimited", unlimited, UserEdit{ExtendDays: 10}, true, 0, 0, 0, true},
+		{"add traffic to unlimited", unlimited, UserEdit{AddTraffic: 5 * gb}, true, 0, 0, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, unaffected := PlanEdits([]models.PasarGuardUser{tt.user}, tt.edit, now)
+			if tt.unaffected {
+				if unaffected != 1 || len(changes) != 0 {
+					t.Fatalf("got %d change(s) %v, want the user unaffected", len(changes), changes)
+				}
+				return
+			}
+			if len(changes) != 1 {
+				t.Fatalf("got %d change(s), want 1", len(changes))
+			}
+			after := changes[0].After
+			if after.ExpiryTime != tt.wantExpiry || after.OnHoldExpireDuration != tt.wantHold {
+				t.Errorf("expiry = %d (on hold %d), want %d (on hold %d)", after.ExpiryTime, after.OnHoldExpireDuration, tt.wantExpiry, tt.wantHold)
+			}
+			if after.TotalGB != tt.wantTotal {
+				t.Errorf("quota = %d, want %d", after.TotalGB, tt.wantTotal)
+			}
+			if after.Enable != tt.wantEnable {
+				t.Errorf("enable = %v, want %v", after.Enable, tt.wantEnable)
+			}
+		})
+	}
+}
+
+func TestApplyChangesPayload(t *testing.T) {
+	var payload map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+		w.Write([]byte(`{"username":"limited"}`))
+	}))
+	defer server.Close()
+	client := clients.NewPasarGuardClient(server.URL, "admin", "secret")
+	client.Token = "token"
+
+	limited := models.PasarGuardUser{Username: "limited", Status: "limited", TotalGB: 10, UsedTraffic: 10, ExpiryTime: time.Now().Unix() + secondsPerDay}
+	changes, _ := PlanEdits([]models.PasarGuardUser{limited}, UserEdit{ExtendDays: 5}, time.Now())
+	if failed := ApplyChanges(client, changes); len(failed) > 0 {
+		t.Fatalf("ApplyChanges failed: %v", failed)
+	}
+	for _, field := range []string{"used_traffic", "lifetime_used_traffic"} {
+		if _, sent := payload[field]; sent {
+			t.Errorf("payload sets %s, usage must be left to the panel", field)
+		}
+	}
+	if payload["status"] != "active" {
+		t.Errorf("status = %v, limited users must not be sent as disabled", payload["status"])
+	}
+}
@@ -0,0 +1,40 @@
+package bulk
+
+import (
+	"reflect"
+	"testing"
+
+	"panels_user_manager/pkg/models"
+)
+
+func TestMatchListed(t *testing.T) {
+	users := []models.PasarGuardUser{
+		{Username: "john_1", UUID: "AAAA-1111"},
+		{Username: "john", UUID: "bbbb-2222"},
+		{Username: "mary_jane", UUID: "cccc-3333"},
+	}
+	tests := []struct {
+		name        string
+		listed      []ListedUser
+		wantMatched []string
+		wantMissing []string
+	}{
+		{"renamed user found by UUID", []ListedUser{{Username: "john", UUID: "aaaa-1111"}}, []string{"john_1"}, nil},
+		{"unknown UUID is missing even if the name is taken", []ListedUser{{Username: "john", UUID: "dddd-4444"}}, nil, []string{"john"}},
+		{"username without UUID", []ListedUser{{Username: " Mary Jane "}}, []string{"mary_jane"}, nil},
+		{"unknown username", []ListedUser{{Username: "nobody"}}, nil, []string{"nobody"}},
+		{"duplicates are matched once", []ListedUser{{Username: "john"}, {Username: "x", UUID: "bbbb-2222"}}, []string{"john"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, missing := MatchListed(users, tt.listed)
+			var names []string
+			for _, u := range matched {
+				names = append(names, u.Username)
+			}
+			if !reflect.DeepEqual(names, tt.wantMatched) || !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("matched %v missing %v, want matched %v missing %v", names, missing, tt.wantMatched, tt.wantMissing)
+			}
+		})
+	}
+}
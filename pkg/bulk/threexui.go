@@ -0,0 +1,219 @@
+package bulk
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+const msPerDay = secondsPerDay * 1000
+
+// ClientFilter selects 3X-UI clients. Unset fields match every client.
+type ClientFilter struct {
+	InboundIDs   []int
+	Email        *regexp.Regexp
+	ExpiringDays int     // fixed expiry between now and now + N days
+	UsageAbove   float64 // percent of the quota; unlimited clients never match
+}
+
+// ClientRef is a client selected for a bulk change, with the raw object from its inbound's
+// settings so fields unknown to this tool are sent back unchanged.
+type ClientRef struct {
+	Inbound models.Inbound
+	Client  models.ClientDetails
+	raw     map[string]interface{}
+}
+
+// SelectClients returns the clients of the inbounds that match the filter. inboundsData must come
+// from ExtractClientsFromInbounds(inbounds), which adds the usage of each client.
+func SelectClients(inbounds []models.Inbound, inboundsData []models.InboundData, filter ClientFilter, now time.Time) []ClientRef {
+	details := make(map[int]map[string]models.ClientDetails)
+	for _, data := range inboundsData {
+		details[data.ID] = make(map[string]models.ClientDetails)
+		for _, c := range data.Clients {
+			details[data.ID][c.ClientEmail] = c
+		}
+	}
+
+	var refs []ClientRef
+	for _, inbound := range inbounds {
+		if len(filter.InboundIDs) > 0 && !containsInt(filter.InboundIDs, inbound.ID) {
+			continue
+		}
+		var settings struct {
+			Clients []map[string]interface{} `json:"clients"`
+		}
+		if strings.TrimSpace(inbound.Settings) == "" || json.Unmarshal([]byte(inbound.Settings), &settings) != nil {
+			continue
+		}
+		for _, raw := range settings.Clients {
+			email, _ := raw["email"].(string)
+			client, ok := details[inbound.ID][email]
+			if !ok || !filter.matches(client, now) {
+				continue
+			}
+			refs = append(refs, ClientRef{Inbound: inbound, Client: client, raw: raw})
+		}
+	}
+	return refs
+}
+
+func (f ClientFilter) matches(client models.ClientDetails, now time.Time) bool {
+	if f.Email != nil && !f.Email.MatchString(client.ClientEmail) {
+		return false
+	}
+	if f.ExpiringDays > 0 {
+		nowMs := now.UnixMilli()
+		if client.ClientExpiryTime <= nowMs || client.ClientExpiryTime > nowMs+int64(f.ExpiringDays)*msPerDay {
+			return false
+		}
+	}
+	if f.UsageAbove > 0 && (client.ClientTotalGB <= 0 || float64(client.TrafficUsed)*100/float64(client.ClientTotalGB) < f.UsageAbove) {
+		return false
+	}
+	return true
+}
+
+// ClientEdit is a change applied to every selected 3X-UI client. Zero fields change nothing.
+type ClientEdit struct {
+	ExtendDays   int   // added to fixed expiries and delayed starts; negative values shorten them
+	FromToday    bool  // already expired clients are extended from today instead of their old expiry
+	AddQuota     int64 // bytes added to finite quotas; negative values take traffic away
+	ResetTraffic bool
+	SetEnable    *bool
+	SetLimitIP   *int
+}
+
+// ClientChange is a planned edit of one client.
+type ClientChange struct {
+	Ref   ClientRef
+	After models.ClientDetails
+}
+
+// PlanClientEdits applies the edit to the clients without sending anything. Clients the edit
+// does not change are only counted.
+func PlanClientEdits(refs []ClientRef, edit ClientEdit, now time.Time) (changes []ClientChange, unaffected int) {
+	nowMs := now.UnixMilli()
+	for _, ref := range refs {
+		after := ref.Client
+		if edit.ExtendDays != 0 {
+			extend := int64(edit.ExtendDays) * msPerDay
+			switch {
+			case after.ClientExpiryTime < 0:
+				// A delayed start is a negative duration, so it grows by going further below 0.
+				after.ClientExpiryTime -= extend
+				if after.ClientExpiryTime > -msPerDay {
+					after.ClientExpiryTime = -msPerDay
+				}
+			case after.ClientExpiryTime > 0:
+				base := after.ClientExpiryTime
+				if edit.FromToday && base < nowMs {
+					base = nowMs
+				}
+				after.ClientExpiryTime = base + extend
+			}
+		}
+		if edit.AddQuota != 0 && after.ClientTotalGB > 0 {
+			after.ClientTotalGB += edit.AddQuota
+			if after.ClientTotalGB < 1 {
+				after.ClientTotalGB = 1 // 0 would mean unlimited
+			}
+		}
+		if edit.ResetTraffic {
+			after.TrafficUsed = 0
+		}
+		if edit.SetLimitIP != nil {
+			after.ClientLimitIP = *edit.SetLimitIP
+		}
+		if edit.SetEnable != nil {
+			after.ClientEnable = *edit.SetEnable
+		} else if !after.ClientEnable && depleted(ref.Client, nowMs) && !depleted(after, nowMs) {
+			// 3X-UI disables clients that run out of traffic or time; give them back what the edit restored.
+			after.ClientEnable = true
+		}
+
+		c := ClientChange{Ref: ref, After: after}
+		if len(c.diffs()) == 0 {
+			unaffected++
+			continue
+		}
+		changes = append(changes, c)
+	}
+	return changes, unaffected
+}
+
+// depleted reports whether a client has run out of traffic or time.
+func depleted(client models.ClientDetails, nowMs int64) bool {
+	return client.ClientTotalGB > 0 && client.TrafficUsed >= client.ClientTotalGB ||
+		client.ClientExpiryTime > 0 && client.ClientExpiryTime <= nowMs
+}
+
+// diffs describes the fields the change modifies.
+func (c ClientChange) diffs() []string {
+	before := c.Ref.Client
+	var diffs []string
+	if before.ClientExpiryTime != c.After.ClientExpiryTime {
+		diffs = append(diffs, fmt.Sprintf("expiry %s → %s", formatClientExpiry(before.ClientExpiryTime), formatClientExpiry(c.After.ClientExpiryTime)))
+	}
+	if before.ClientTotalGB != c.After.ClientTotalGB {
+		diffs = append(diffs, fmt.Sprintf("quota %s → %s", formatQuota(before.ClientTotalGB), formatQuota(c.After.ClientTotalGB)))
+	}
+	if before.TrafficUsed != c.After.TrafficUsed {
+		diffs = append(diffs, fmt.Sprintf("used %s → %s", utils.FormatBytes(before.TrafficUsed), utils.FormatBytes(c.After.TrafficUsed)))
+	}
+	if before.ClientEnable != c.After.ClientEnable {
+		diffs = append(diffs, fmt.Sprintf("enabled %v → %v", before.ClientEnable, c.After.ClientEnable))
+	}
+	if before.ClientLimitIP != c.After.ClientLimitIP {
+		diffs = append(diffs, fmt.Sprintf("IP limit %d → %d", before.ClientLimitIP, c.After.ClientLimitIP))
+	}
+	return diffs
+}
+
+// PrintClientChanges previews planned edits, one line per client with the fields that change.
+func PrintClientChanges(changes []ClientChange, unaffected int) {
+	for _, c := range changes {
+		fmt.Printf(" "+utils.ColorYellow+"~ %-25s"+utils.ColorReset+" %-16s %s\n", truncate(c.Ref.Client.ClientEmail, 25),
+			truncate(c.Ref.Inbound.Remark, 16), strings.Join(c.diffs(), ", "))
+	}
+	fmt.Printf(" "+utils.ColorDim+"%d client(s) to change, %d unaffected (unlimited quota or expiry, or already in that state)"+utils.ColorReset+"\n", len(changes), unaffected)
+}
+
+// ApplyClientChanges sends the planned edits through the client-level endpoints, so the other
+// clients of each inbound are not touched. It returns the errors by email.
+func ApplyClientChanges(client *clients.ThreeXUIClient, changes []ClientChange) map[string]error {
+	failed := make(map[string]error)
+	for i, c := range changes {
+		email := c.Ref.Client.ClientEmail
+		raw := c.Ref.raw
+		raw["expiryTime"] = c.After.ClientExpiryTime
+		raw["totalGB"] = c.After.ClientTotalGB
+		raw["enable"] = c.After.ClientEnable
+		raw["limitIp"] = c.After.ClientLimitIP
+		err := client.UpdateClient(c.Ref.Inbound.ID, clients.ClientKey(c.Ref.Inbound.Protocol, raw), raw)
+		if err == nil && c.After.TrafficUsed != c.Ref.Client.TrafficUsed {
+			err = client.ResetClientTraffic(c.Ref.Inbound.ID, email)
+		}
+		if err != nil {
+			failed[email] = err
+			fmt.Printf(" "+utils.ColorRed+"[%d/%d] ✗ %s: %v"+utils.ColorReset+"\n", i+1, len(changes), email, err)
+			continue
+		}
+		fmt.Printf(" "+utils.ColorGreen+"[%d/%d] ✓ %s"+utils.ColorReset+"\n", i+1, len(changes), email)
+	}
+	return failed
+}
+
+// formatClientExpiry renders a 3X-UI expiry in milliseconds; negative values are delayed starts.
+func formatClientExpiry(expiry int64) string {
+	if expiry < 0 {
+		return fmt.Sprintf("%dd after use", -expiry/msPerDay)
+	}
+	return formatExpiry(expiry/1000, 0)
+}
@@ -0,0 +1,114 @@
+package bulk
+
+import (
+	"testing"
+	"time"
+
+	"panels_user_manager/pkg/models"
+)
+
+func TestPlanClientEdits(t *testing.T) {
+	const gb = 1 << 30
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	nowMs := now.UnixMilli()
+	limit := 3
+
+	active := models.ClientDetails{ClientEmail: "active", ClientEnable: true, ClientTotalGB: 10 * gb, TrafficUsed: 2 * gb, ClientExpiryTime: nowMs + 5*msPerDay}
+	delayed := models.ClientDetails{ClientEmail: "delayed", ClientEnable: true, ClientExpiryTime: -30 * msPerDay}
+	depleted := models.ClientDetails{ClientEmail: "depleted", ClientTotalGB: 10 * gb, TrafficUsed: 10 * gb, ClientExpiryTime: nowMs + 5*msPerDay}
+	expired := models.ClientDetails{ClientEmail: "expired", ClientTotalGB: 10 * gb, ClientExpiryTime: nowMs - 3*msPerDay}
+	disabled := models.ClientDetails{ClientEmail: "disabled", ClientTotalGB: 10 * gb, ClientExpiryTime: nowMs + 5*msPerDay}
+	unlimited := models.ClientDetails{ClientEmail: "unlimited", ClientEnable: true}
+
+	tests := []struct {
+		name       string
+		client     models.ClientDetails
+		edit       ClientEdit
+		unaffected bool
+		want       models.ClientDetails
+	}{
+		{"extend", active, ClientEdit{ExtendDays: 10}, false, with(active, func(c *models.ClientDetails) { c.ClientExpiryTime += 10 * msPerDay })},
+		{"extend delayed start", delayed, ClientEdit{ExtendDays: 10}, false, with(delayed, func(c *models.ClientDetails) { c.ClientExpiryTime = -40 * msPerDay })},
+		{"delayed start never below a day", delayed, ClientEdit{ExtendDays: -40}, false, with(delayed, func(c *models.ClientDetails) { c.ClientExpiryTime = -msPerDay })},
+		{"extend expired from today re-enables", expired, ClientEdit{ExtendDays: 10, FromToday: true}, false, with(expired, func(c *models.ClientDetails) {
+			c.ClientExpiryTime, c.ClientEnable = nowMs+10*msPerDay, true
+		})},
+		{"extend expired not far enough stays disabled", expired, ClientEdit{ExtendDays: 2}, false, with(expired, func(c *models.ClientDetails) { c.ClientExpiryTime += 2 * msPerDay })},
+		{"add quota re-enables depleted", depleted, ClientEdit{AddQuota: 5 * gb}, false, with(depleted, func(c *models.ClientDetails) {
+			c.ClientTotalGB, c.ClientEnable = 15*gb, true
+		})},
+		{"reset traffic re-enables depleted", depleted, ClientEdit{ResetTraffic: true}, false, with(depleted, func(c *models.ClientDetails) {
+			c.TrafficUsed, c.ClientEnable = 0, true
+		})},
+		{"extend disabled stays disabled", disabled, ClientEdit{ExtendDays: 10}, false, with(disabled, func(c *models.ClientDetails) { c.ClientExpiryTime += 10 * msPerDay })},
+		{"take quota keeps it finite", active, ClientEdit{AddQuota: -20 * gb}, false, with(active, func(c *models.ClientDetails) { c.ClientTotalGB = 1 })},
+		{"set IP limit", active, ClientEdit{SetLimitIP: &limit}, false, with(active, func(c *models.ClientDetails) { c.ClientLimitIP = 3 })},
+		{"add quota to unlimited", unlimited, ClientEdit{AddQuota: 5 * gb}, true, unlimited},
+		{"extend unlimited", unlimited, ClientEdit{ExtendDays: 10}, true, unlimited},
+		{"reset unused traffic", unlimited, ClientEdit{ResetTraffic: true}, true, unlimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, unaffected := PlanClientEdits([]ClientRef{{Client: tt.client}}, tt.edit, now)
+			if tt.unaffected {
+				if unaffected != 1 || len(changes) != 0 {
+					t.Fatalf("got %d change(s), want the client unaffected", len(changes))
+				}
+				return
+			}
+			if len(changes) != 1 {
+				t.Fatalf("got %d change(s), want 1", len(changes))
+			}
+			if got := changes[0].After; got != tt.want {
+				t.Errorf("after = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectClients(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	nowMs := now.UnixMilli()
+	inbounds := []models.Inbound{
+		{ID: 1, Settings: `{"clients":[{"email":"a@x"},{"email":"b@x"}]}`},
+		{ID: 2, Settings: `{"clients":[{"email":"c@y"}]}`},
+	}
+	data := []models.InboundData{
+		{ID: 1, Clients: []models.ClientDetails{
+			{ClientEmail: "a@x", ClientTotalGB: 100, TrafficUsed: 90, ClientExpiryTime: nowMs + 2*msPerDay},
+			{ClientEmail: "b@x", ClientTotalGB: 100, TrafficUsed: 10, ClientExpiryTime: nowMs + 20*msPerDay},
+		}},
+		{ID: 2, Clients: []models.ClientDetails{{ClientEmail: "c@y", TrafficUsed: 500}}},
+	}
+	tests := []struct {
+		name   string
+		filter ClientFilter
+		want   []string
+	}{
+		{"all", ClientFilter{}, []string{"a@x", "b@x", "c@y"}},
+		{"inbound", ClientFilter{InboundIDs: []int{2}}, []string{"c@y"}},
+		{"expiring", ClientFilter{ExpiringDays: 7}, []string{"a@x"}},
+		{"usage skips unlimited", ClientFilter{UsageAbove: 50}, []string{"a@x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, ref := range SelectClients(inbounds, data, tt.filter, now) {
+				got = append(got, ref.Client.ClientEmail)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func with(c models.ClientDetails, change func(*models.ClientDetails)) models.ClientDetails {
+	change(&c)
+	return c
+}
@@ -0,0 +1,48 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"panels_user_manager/pkg/models"
+)
+
+// isAccountProtocol reports whether an inbound authenticates with user/password accounts
+// instead of VPN clients.
+func isAccountProtocol(protocol string) bool {
+	return protocol == "socks" || protocol == "http" || protocol == "mixed"
+}
+
+// accountSettings rebuilds the settings of a socks, http or mixed inbound: the original settings
+// (auth mode, udp, ip, ...) are kept and the accounts are taken from the export.
+func accountSettings(inboundData models.InboundData) (string, error) {
+	settings := make(map[string]interface{})
+	if strings.TrimSpace(inboundData.OriginalSettings) != "" {
+		if err := json.Unmarshal([]byte(inboundData.OriginalSettings), &settings); err != nil {
+			return "", fmt.Errorf("could not parse settings: %v", err)
+		}
+	}
+	delete(settings, "clients")
+	accounts := []models.Account{}
+	for _, account := range inboundData.Accounts {
+		if account.User == "" {
+			return "", fmt.Errorf("account without user name")
+		}
+		accounts = append(accounts, account)
+	}
+	if len(accounts) > 0 {
+		settings["accounts"] = accounts
+		if inboundData.Protocol != "http" {
+			settings["auth"] = "password"
+		}
+	} else if _, exported := settings["accounts"]; !exported {
+		settings["accounts"] = accounts
+	}
+
+	settingsBytes, err := json.Marshal(settings)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal settings: %v", err)
+	}
+	return string(settingsBytes), nil
+}
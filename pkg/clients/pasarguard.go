@@ -178,17 +178,8 @@ func (c *PasarGuardClient) GetAllGroups() ([]models.PasarGuardGroup, error) {
 		return nil, fmt.Errorf("not authenticated. Please login first")
 	}
 
-	endpoints := []string{
-		"/api/groups",
-		"/api/admin/groups",
-		"/api/v1/groups",
-		"/api/group",
-		"/api/admin/group",
-		"/api/v1/group",
-	}
-
 	var lastErr error
-	for _, ep := range endpoints {
+	for _, ep := range groupEndpoints {
 		reqURL := fmt.Sprintf("%s%s", c.BaseURL, ep)
 		req, err := http.NewRequest("GET", reqURL, nil)
 		if err != nil {
@@ -211,8 +202,9 @@ func (c *PasarGuardClient) GetAllGroups() ([]models.PasarGuardGroup, error) {
 			continue
 		}
 
+		// An empty list is a valid answer: a fresh panel has no groups yet.
 		var groups []models.PasarGuardGroup
-		if err := json.Unmarshal(bodyBytes, &groups); err == nil && len(groups) > 0 {
+		if err := json.Unmarshal(bodyBytes, &groups); err == nil {
 			return groups, nil
 		}
 
@@ -242,7 +234,7 @@ func (c *PasarGuardClient) GetAllGroups() ([]models.PasarGuardGroup, error) {
 
 		var wrapper map[string]interface{}
 		if err := json.Unmarshal(bodyBytes, &wrapper); err == nil {
-			if arr, ok := wrapper["groups"].([]interface{}); ok && len(arr) > 0 {
+			if arr, ok := wrapper["groups"].([]interface{}); ok {
 				out := make([]models.PasarGuardGroup, 0, len(arr))
 				for _, a := range arr {
 					if m, ok := a.(map[string]interface{}); ok {
@@ -259,9 +251,7 @@ func (c *PasarGuardClient) GetAllGroups() ([]models.PasarGuardGroup, error) {
 						out = append(out, models.PasarGuardGroup{ID: id, Name: name})
 					}
 				}
-				if len(out) > 0 {
-					return out, nil
-				}
+				return out, nil
 			}
 		}
 
@@ -398,7 +388,8 @@ func (c *PasarGuardClient) tryGetUsers(path, method string) ([]models.PasarGuard
 					Username:        apiUser.Username,
 					Email:           email,
 					UUID:            uuid,
-					Enable:          apiUser.Status == "active",
+					Enable:          apiUser.Status == "active" || apiUser.Status == "on_hold",
+					Status:          apiUser.Status,
 					TotalGB:         apiUser.DataLimit,
 					ExpiryTime:      expiryTime,
 					LimitIP:         0,
@@ -409,6 +400,13 @@ func (c *PasarGuardClient) tryGetUsers(path, method string) ([]models.PasarGuard
 					SubscriptionURL: apiUser.SubscriptionURL,
 					ProxySettings:   apiUser.ProxySettings,
 					GroupIDs:        apiUser.GroupIDs,
+
+					DataLimitResetStrategy: apiUser.DataLimitResetStrategy,
+				}
+
+				if apiUser.Status == "on_hold" && apiUser.OnHoldExpireDuration != nil {
+					user.OnHoldExpireDuration = int64(*apiUser.OnHoldExpireDuration)
+					user.OnHoldTimeout = parseTimestamp(apiUser.OnHoldTimeout)
 				}
 
 				if apiUser.Note != nil && *apiUser.Note != "" {
@@ -483,6 +481,24 @@ func (c *PasarGuardClient) tryGetUsers(path, method string) ([]models.PasarGuard
 	return nil, fmt.Errorf("server returned status %d from %s %s. Response: %s", resp.StatusCode, method, reqURL, string(bodyBytes))
 }
 
+// parseTimestamp reads a timestamp that may be an ISO 8601 string or a unix number; anything else reads as 0.
+func parseTimestamp(raw json.RawMessage) int64 {
+	var text string
+	if err := json.Unmarshal(raw, &text); err == nil {
+		for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05.999999", "2006-01-02T15:04:05"} {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t.Unix()
+			}
+		}
+		return 0
+	}
+	var number float64
+	if err := json.Unmarshal(raw, &number); err == nil {
+		return int64(number)
+	}
+	return 0
+}
+
 // discoverEndpoints tries to discover API endpoints from OpenAPI/Swagger docs
 func (c *PasarGuardClient) discoverEndpoints() ([]struct{ path, method string }, error) {
 	docsEndpoints := []string{
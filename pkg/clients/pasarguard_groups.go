@@ -0,0 +1,164 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// groupEndpoints are the group API paths used by different PasarGuard versions.
+var groupEndpoints = []string{
+	"/api/groups",
+	"/api/admin/groups",
+	"/api/v1/groups",
+	"/api/group",
+	"/api/admin/group",
+	"/api/v1/group",
+}
+
+// CreateGroup creates a group with access to the given inbound tags and returns it.
+func (c *PasarGuardClient) CreateGroup(name string, inboundTags []string) (models.PasarGuardGroup, error) {
+	if c.Token == "" {
+		return models.PasarGuardGroup{}, fmt.Errorf("not authenticated. Please login first")
+	}
+	if inboundTags == nil {
+		inboundTags = []string{}
+	}
+	payloadBytes, err := json.Marshal(map[string]interface{}{
+		"name":         name,
+		"inbound_tags": inboundTags,
+		"is_disabled":  false,
+	})
+	if err != nil {
+		return models.PasarGuardGroup{}, fmt.Errorf("error marshalling group payload: %v", err)
+	}
+
+	// Newer panels create groups on the singular path and list them on the plural one,
+	// so the singular paths are tried first.
+	endpoints := append(append([]string{}, groupEndpoints[3:]...), groupEndpoints[:3]...)
+	var lastErr error
+	for _, ep := range endpoints {
+		reqURL := fmt.Sprintf("%s%s", c.BaseURL, ep)
+		req, err := http.NewRequest("POST", reqURL, bytes.NewBuffer(payloadBytes))
+		if err != nil {
+			lastErr = fmt.Errorf("error creating request for %s: %v", reqURL, err)
+			continue
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("error requesting %s: %v", reqURL, err)
+			continue
+		}
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		utils.VerboseLog("CreateGroup response from %s: status=%d, body=%s", reqURL, resp.StatusCode, string(bodyBytes))
+
+		if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+			lastErr = fmt.Errorf("endpoint %s not available (status %d)", reqURL, resp.StatusCode)
+			continue
+		}
+		if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+			return models.PasarGuardGroup{}, fmt.Errorf("server returned status %d from %s. Response: %s", resp.StatusCode, reqURL, string(bodyBytes))
+		}
+
+		var group models.PasarGuardGroup
+		if err := json.Unmarshal(bodyBytes, &group); err == nil && group.ID != 0 {
+			return group, nil
+		}
+		// Some versions answer without the created object; look it up by name instead.
+		groups, err := c.GetAllGroups()
+		if err != nil {
+			return models.PasarGuardGroup{}, fmt.Errorf("group created but could not be read back: %v", err)
+		}
+		for _, g := range groups {
+			if g.Name == name {
+				return g, nil
+			}
+		}
+		return models.PasarGuardGroup{}, fmt.Errorf("group created but not found in the groups list")
+	}
+
+	return models.PasarGuardGroup{}, fmt.Errorf("failed to create group after trying multiple endpoints. Last error: %v", lastErr)
+}
+
+// GetInboundTags fetches the inbound tags configured on the panel's cores.
+func (c *PasarGuardClient) GetInboundTags() ([]string, error) {
+	if c.Token == "" {
+		return nil, fmt.Errorf("not authenticated. Please login first")
+	}
+
+	endpoints := []string{
+		"/api/inbounds",
+		"/api/core/inbounds",
+		"/api/v1/inbounds",
+	}
+
+	var lastErr error
+	for _, ep := range endpoints {
+		reqURL := fmt.Sprintf("%s%s", c.BaseURL, ep)
+		req, err := http.NewRequest("GET", reqURL, nil)
+		if err != nil {
+			lastErr = fmt.Errorf("error creating request for %s: %v", reqURL, err)
+			continue
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("error requesting %s: %v", reqURL, err)
+			continue
+		}
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			lastErr = fmt.Errorf("non-200 status %d from %s: %s", resp.StatusCode, reqURL, string(bodyBytes))
+			continue
+		}
+
+		// Plain list of tags
+		var tags []string
+		if err := json.Unmarshal(bodyBytes, &tags); err == nil {
+			return tags, nil
+		}
+		// List of inbound objects; a failed decode above may have left empty entries
+		tags = nil
+		var objects []struct {
+			Tag string `json:"tag"`
+		}
+		if err := json.Unmarshal(bodyBytes, &objects); err == nil {
+			for _, o := range objects {
+				tags = append(tags, o.Tag)
+			}
+			return tags, nil
+		}
+		// Inbound objects grouped by protocol
+		tags = nil
+		var byProtocol map[string][]struct {
+			Tag string `json:"tag"`
+		}
+		if err := json.Unmarshal(bodyBytes, &byProtocol); err == nil {
+			for _, list := range byProtocol {
+				for _, o := range list {
+					tags = append(tags, o.Tag)
+				}
+			}
+			sort.Strings(tags)
+			return tags, nil
+		}
+
+		lastErr = fmt.Errorf("unrecognized inbounds response from %s", reqURL)
+	}
+
+	return nil, fmt.Errorf("failed to fetch inbound tags after trying multiple endpoints. Last error: %v", lastErr)
+}
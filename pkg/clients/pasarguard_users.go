@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
@@ -18,6 +19,7 @@ func (c *PasarGuardClient) AddUser(user models.PasarGuardUser) error {
 	if c.Token == "" {
 		return fmt.Errorf("not authenticated. Please login first")
 	}
+	user = withDelayedStart(user)
 
 	endpoints := []string{
 		"/api/user",
@@ -32,32 +34,15 @@ func (c *PasarGuardClient) AddUser(user models.PasarGuardUser) error {
 		expireStr = time.Unix(user.ExpiryTime, 0).Format(time.RFC3339)
 	}
 
-	proxySettings := make(map[string]interface{})
-	switch user.Protocol {
-	case "vmess":
-		proxySettings["vmess"] = map[string]interface{}{
-			"id": user.UUID,
-		}
-	case "vless":
-		proxySettings["vless"] = map[string]interface{}{
-			"id":   user.UUID,
-			"flow": "",
-		}
-	case "trojan":
-		proxySettings["trojan"] = map[string]interface{}{
-			"password": user.UUID,
-		}
-	case "shadowsocks":
-		proxySettings["shadowsocks"] = map[string]interface{}{
-			"password": user.UUID,
-			"method":   "chacha20-ietf-poly1305",
-		}
-	}
+	proxySettings := buildProxySettings(user)
 
 	status := "active"
 	if !user.Enable {
 		status = "disabled"
 	}
+	if user.Enable && user.OnHoldExpireDuration > 0 {
+		status = "on_hold"
+	}
 
 	payload := map[string]interface{}{
 		"username":       user.Username,
@@ -74,6 +59,15 @@ func (c *PasarGuardClient) AddUser(user models.PasarGuardUser) error {
 	if expireStr != "" {
 		payload["expire"] = expireStr
 	}
+	if user.DataLimitResetStrategy != "" {
+		payload["data_limit_reset_strategy"] = user.DataLimitResetStrategy
+	}
+	if user.OnHoldExpireDuration > 0 {
+		payload["on_hold_expire_duration"] = user.OnHoldExpireDuration
+		if user.OnHoldTimeout > 0 {
+			payload["on_hold_timeout"] = time.Unix(user.OnHoldTimeout, 0).Format(time.RFC3339)
+		}
+	}
 
 	if user.UsedTraffic >= 0 {
 		payload["used_traffic"] = user.UsedTraffic
@@ -191,6 +185,7 @@ func (c *PasarGuardClient) UpdateUserByIdentifier(identifier string, user models
 	if c.Token == "" {
 		return fmt.Errorf("not authenticated. Please login first")
 	}
+	user = withDelayedStart(user)
 
 	endpoints := []struct {
 		path   string
@@ -209,22 +204,15 @@ func (c *PasarGuardClient) UpdateUserByIdentifier(identifier string, user models
 		expireStr = time.Unix(user.ExpiryTime, 0).Format(time.RFC3339)
 	}
 
-	proxySettings := make(map[string]interface{})
-	switch user.Protocol {
-	case "vmess":
-		proxySettings["vmess"] = map[string]interface{}{"id": user.UUID}
-	case "vless":
-		proxySettings["vless"] = map[string]interface{}{"id": user.UUID, "flow": ""}
-	case "trojan":
-		proxySettings["trojan"] = map[string]interface{}{"password": user.UUID}
-	case "shadowsocks":
-		proxySettings["shadowsocks"] = map[string]interface{}{"password": user.UUID, "method": "chacha20-ietf-poly1305"}
-	}
+	proxySettings := buildProxySettings(user)
 
 	status := "active"
 	if !user.Enable {
 		status = "disabled"
 	}
+	if user.Enable && user.OnHoldExpireDuration > 0 {
+		status = "on_hold"
+	}
 
 	payload := map[string]interface{}{
 		"username":       user.Username,
@@ -239,6 +227,15 @@ func (c *PasarGuardClient) UpdateUserByIdentifier(identifier string, user models
 	if expireStr != "" {
 		payload["expire"] = expireStr
 	}
+	if user.DataLimitResetStrategy != "" {
+		payload["data_limit_reset_strategy"] = user.DataLimitResetStrategy
+	}
+	if user.OnHoldExpireDuration > 0 {
+		payload["on_hold_expire_duration"] = user.OnHoldExpireDuration
+		if user.OnHoldTimeout > 0 {
+			payload["on_hold_timeout"] = time.Unix(user.OnHoldTimeout, 0).Format(time.RFC3339)
+		}
+	}
 	if user.UsedTraffic >= 0 {
 		payload["used_traffic"] = user.UsedTraffic
 		payload["lifetime_used_traffic"] = user.UsedTraffic
@@ -323,6 +320,113 @@ func (c *PasarGuardClient) UpdateUserByIdentifier(identifier string, user models
 	return fmt.Errorf("failed to update user after trying multiple endpoints and methods. Last error: %v", lastErr)
 }
 
+// DeleteUser removes a user from the panel.
+func (c *PasarGuardClient) DeleteUser(username string) error {
+	if c.Token == "" {
+		return fmt.Errorf("not authenticated. Please login first")
+	}
+
+	endpoints := []string{
+		fmt.Sprintf("/api/user/%s", url.PathEscape(username)),
+		fmt.Sprintf("/api/users/%s", url.PathEscape(username)),
+		fmt.Sprintf("/api/v1/user/%s", url.PathEscape(username)),
+	}
+
+	var lastErr error
+	for _, ep := range endpoints {
+		reqURL := fmt.Sprintf("%s%s", c.BaseURL, ep)
+		req, err := http.NewRequest("DELETE", reqURL, nil)
+		if err != nil {
+			lastErr = fmt.Errorf("error creating request for DELETE %s: %v", reqURL, err)
+			continue
+		}
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+
+		resp, err := c.HttpClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("error making request to DELETE %s: %v", reqURL, err)
+			continue
+		}
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		utils.VerboseLog("DeleteUser response from DELETE %s: status=%d, body=%s", reqURL, resp.StatusCode, string(bodyBytes))
+
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusNoContent:
+			return nil
+		case http.StatusUnauthorized:
+			return fmt.Errorf("authentication failed. Token may have expired. Please login again")
+		case http.StatusNotFound:
+			// The right endpoint answers 404 for unknown users too; only its body tells them apart.
+			if strings.Contains(strings.ToLower(string(bodyBytes)), "user not found") {
+				return fmt.Errorf("user %s not found", username)
+			}
+			lastErr = fmt.Errorf("endpoint not found: DELETE %s", reqURL)
+			continue
+		case http.StatusMethodNotAllowed:
+			lastErr = fmt.Errorf("method DELETE not allowed for %s", reqURL)
+			continue
+		}
+		lastErr = fmt.Errorf("server returned status %d from DELETE %s. Response: %s", resp.StatusCode, reqURL, string(bodyBytes))
+	}
+
+	return fmt.Errorf("failed to delete user after trying multiple endpoints. Last error: %v", lastErr)
+}
+
+// defaultShadowsocksMethod is used when a shadowsocks credential comes without a method.
+const defaultShadowsocksMethod = "chacha20-ietf-poly1305"
+
+// buildProxySettings returns the proxy_settings to send for a user. Settings carried by the user
+// (every protocol, with flow and method, as exported from PasarGuard or merged from 3X-UI) are sent
+// as they are; only a missing entry for the user's own protocol is synthesized from its UUID.
+func buildProxySettings(user models.PasarGuardUser) map[string]interface{} {
+	proxySettings := make(map[string]interface{})
+	for protocol, raw := range user.ProxySettings {
+		settings, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		entry := make(map[string]interface{}, len(settings))
+		for k, v := range settings {
+			entry[k] = v
+		}
+		if protocol == "shadowsocks" {
+			if method, _ := entry["method"].(string); method == "" {
+				entry["method"] = defaultShadowsocksMethod
+			}
+		}
+		proxySettings[protocol] = entry
+	}
+	if _, ok := proxySettings[user.Protocol]; ok || user.UUID == "" {
+		return proxySettings
+	}
+
+	switch user.Protocol {
+	case "vmess":
+		proxySettings["vmess"] = map[string]interface{}{"id": user.UUID}
+	case "vless":
+		proxySettings["vless"] = map[string]interface{}{"id": user.UUID, "flow": ""}
+	case "trojan":
+		proxySettings["trojan"] = map[string]interface{}{"password": user.UUID}
+	case "shadowsocks":
+		proxySettings["shadowsocks"] = map[string]interface{}{"password": user.UUID, "method": defaultShadowsocksMethod}
+	}
+	return proxySettings
+}
+
+// withDelayedStart maps a 3X-UI delayed start (a negative ExpiryTime holding the validity in
+// milliseconds, counted from the first connection) to PasarGuard's on_hold status.
+func withDelayedStart(user models.PasarGuardUser) models.PasarGuardUser {
+	if user.ExpiryTime < 0 {
+		if user.OnHoldExpireDuration <= 0 {
+			user.OnHoldExpireDuration = -user.ExpiryTime / 1000
+		}
+		user.ExpiryTime = 0
+	}
+	return user
+}
+
 // setUserTraffic attempts to set the used traffic for a user via a separate API call
 func (c *PasarGuardClient) setUserTraffic(username string, usedTraffic int64) error {
 	if c.Token == "" {
@@ -0,0 +1,121 @@
+package clients
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"panels_user_manager/pkg/models"
+)
+
+// shadowsocks2022KeyLengths is the key size in bytes of each Shadowsocks 2022 method. Server PSKs
+// and user keys of these methods are base64 strings of exactly this length.
+var shadowsocks2022KeyLengths = map[string]int{
+	"2022-blake3-aes-128-gcm":       16,
+	"2022-blake3-aes-256-gcm":       32,
+	"2022-blake3-chacha20-poly1305": 32,
+}
+
+// shadowsocksLegacyMethods are the pre-2022 methods Xray accepts, per user or per inbound.
+var shadowsocksLegacyMethods = map[string]bool{
+	"aes-128-gcm":             true,
+	"aes-256-gcm":             true,
+	"chacha20-poly1305":       true,
+	"chacha20-ietf-poly1305":  true,
+	"xchacha20-poly1305":      true,
+	"xchacha20-ietf-poly1305": true,
+	"none":                    true,
+	"plain":                   true,
+}
+
+// ShadowsocksSettings rebuilds a shadowsocks inbound's settings from an export: the inbound method
+// and server password (the PSK for 2022 methods) are kept from the original settings, and every
+// client keeps its own method and password. Settings the target cannot represent are an error,
+// so the inbound is skipped instead of being created broken or with weak credentials.
+func ShadowsocksSettings(inboundData models.InboundData) (string, error) {
+	settings := make(map[string]interface{})
+	if strings.TrimSpace(inboundData.OriginalSettings) != "" {
+		if err := json.Unmarshal([]byte(inboundData.OriginalSettings), &settings); err != nil {
+			return "", fmt.Errorf("could not parse shadowsocks settings: %v", err)
+		}
+	}
+	method, _ := settings["method"].(string)
+	serverPassword, _ := settings["password"].(string)
+	keyLen, is2022 := shadowsocks2022KeyLengths[method]
+	switch {
+	case method == "":
+		return "", fmt.Errorf("shadowsocks inbound has no method in its exported settings")
+	case is2022:
+		if err := checkShadowsocks2022Key(serverPassword, keyLen); err != nil {
+			return "", fmt.Errorf("server PSK for %s: %v", method, err)
+		}
+	case !shadowsocksLegacyMethods[method]:
+		return "", fmt.Errorf("shadowsocks method '%s' is not supported by 3X-UI", method)
+	}
+
+	clientSettings := []map[string]interface{}{}
+	for _, cd := range inboundData.Clients {
+		cs, err := ShadowsocksClient(method, cd)
+		if err != nil {
+			return "", err
+		}
+		clientSettings = append(clientSettings, cs)
+	}
+	settings["clients"] = clientSettings
+
+	settingsBytes, err := json.Marshal(settings)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal shadowsocks settings: %v", err)
+	}
+	return string(settingsBytes), nil
+}
+
+// ShadowsocksClient builds the raw client object for a client of a shadowsocks inbound using
+// method. Clients of 2022 methods must use the inbound's method with a key of its length; other
+// clients may use any legacy method and default to the inbound's.
+func ShadowsocksClient(method string, cd models.ClientDetails) (map[string]interface{}, error) {
+	if cd.ClientPassword == "" {
+		return nil, fmt.Errorf("client '%s' has no password in the export (files from older versions must be exported again)", cd.ClientEmail)
+	}
+	clientMethod := cd.ClientMethod
+	if keyLen, is2022 := shadowsocks2022KeyLengths[method]; is2022 {
+		if clientMethod != "" && clientMethod != method {
+			return nil, fmt.Errorf("client '%s' uses %s inside a %s inbound", cd.ClientEmail, clientMethod, method)
+		}
+		if err := checkShadowsocks2022Key(cd.ClientPassword, keyLen); err != nil {
+			return nil, fmt.Errorf("key of client '%s': %v", cd.ClientEmail, err)
+		}
+	} else {
+		if clientMethod == "" {
+			clientMethod = method
+		}
+		if !shadowsocksLegacyMethods[clientMethod] {
+			return nil, fmt.Errorf("client '%s' uses method '%s', which a %s inbound cannot serve", cd.ClientEmail, clientMethod, method)
+		}
+	}
+	return map[string]interface{}{
+		"method":     clientMethod,
+		"password":   cd.ClientPassword,
+		"email":      cd.ClientEmail,
+		"enable":     cd.ClientEnable,
+		"totalGB":    cd.ClientTotalGB,
+		"expiryTime": cd.ClientExpiryTime,
+		"limitIp":    cd.ClientLimitIP,
+		"subId":      cd.ClientSubID,
+		"tgId":       cd.ClientTgID,
+		"reset":      cd.ClientReset,
+	}, nil
+}
+
+// checkShadowsocks2022Key verifies that key is base64 for exactly keyLen bytes.
+func checkShadowsocks2022Key(key string, keyLen int) error {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return fmt.Errorf("not valid base64")
+	}
+	if len(decoded) != keyLen {
+		return fmt.Errorf("decodes to %d bytes, %d required", len(decoded), keyLen)
+	}
+	return nil
+}
@@ -13,8 +13,6 @@ import (
 	"time"
 
 	"panels_user_manager/pkg/models"
-
-	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
 // ThreeXUIClient is the client to manage communication with the 3x-ui panel.
@@ -141,32 +139,30 @@ func (c *ThreeXUIClient) AddInbound(inboundData models.InboundData) error {
 	}
 	// --- THE MAIN FIX: REBUILD THE SETTINGS OBJECT FROM SCRATCH ---
 	if inboundData.Protocol == "wireguard" {
-		fmt.Println("→ Detected WireGuard protocol. Generating new cryptographic keys...")
-		privateKey, err := wgtypes.GeneratePrivateKey()
+		// Server keys and peers are kept as exported, like UpdateInbound does, so existing client
+		// configs keep working. Rotation is done by the importer before the inbound gets here.
+		settings, generated, err := EnsureWireGuardKey(inboundData.OriginalSettings)
 		if err != nil {
-			return fmt.Errorf("failed to generate wireguard private key: %v", err)
+			return fmt.Errorf("invalid wireguard settings for '%s': %v", inboundData.Remark, err)
 		}
-		publicKey := privateKey.PublicKey()
-		var originalWgSettings map[string]interface{}
-		if err := json.Unmarshal([]byte(inboundData.OriginalSettings), &originalWgSettings); err != nil {
-			originalWgSettings = make(map[string]interface{})
-			fmt.Printf(" Warning: Could not parse original WireGuard settings for '%s'. Peers will not be migrated.\n", inboundData.Remark)
+		if generated {
+			fmt.Printf(" Warning: '%s' had no server key; a new one was generated and its peers need new configs.\n", inboundData.Remark)
 		}
-		newWgSettings := map[string]interface{}{
-			"privateKey": privateKey.String(),
-			"publicKey":  publicKey.String(),
-			"peers":      originalWgSettings["peers"],
-			"mtu":        originalWgSettings["mtu"],
-			"listenPort": originalWgSettings["listenPort"],
+		finalSettings = settings
+	} else if inboundData.Protocol == "shadowsocks" {
+		settings, err := ShadowsocksSettings(inboundData)
+		if err != nil {
+			return fmt.Errorf("cannot import shadowsocks inbound '%s': %v", inboundData.Remark, err)
 		}
-		settingsBytes, err := json.Marshal(newWgSettings)
+		finalSettings = settings
+	} else if isAccountProtocol(inboundData.Protocol) {
+		settings, err := accountSettings(inboundData)
 		if err != nil {
-			return fmt.Errorf("failed to marshal new wireguard settings: %v", err)
+			return fmt.Errorf("cannot import %s inbound '%s': %v", inboundData.Protocol, inboundData.Remark, err)
 		}
-		finalSettings = string(settingsBytes)
-		fmt.Println(" ✓ New keys generated and settings object rebuilt.")
+		finalSettings = settings
 	} else {
-		// For client-based protocols (VLESS, Trojan, Vmess, Shadowsocks):
+		// For client-based protocols (VLESS, Trojan, Vmess):
 		clientSettings := []models.ClientSetting{}
 		for _, cd := range inboundData.Clients {
 			cs := models.ClientSetting{
@@ -180,6 +176,7 @@ func (c *ThreeXUIClient) AddInbound(inboundData models.InboundData) error {
 				SubID:      cd.ClientSubID,
 				TgID:       cd.ClientTgID,
 				Reset:      cd.ClientReset,
+				Password:   cd.ClientPassword,
 			}
 			clientSettings = append(clientSettings, cs)
 		}
@@ -250,6 +247,18 @@ func (c *ThreeXUIClient) UpdateInbound(inboundID int, inboundData models.Inbound
 		} else {
 			finalSettings = "{\"peers\":[]}"
 		}
+	} else if inboundData.Protocol == "shadowsocks" {
+		settings, err := ShadowsocksSettings(inboundData)
+		if err != nil {
+			return fmt.Errorf("cannot import shadowsocks inbound '%s': %v", inboundData.Remark, err)
+		}
+		finalSettings = settings
+	} else if isAccountProtocol(inboundData.Protocol) {
+		settings, err := accountSettings(inboundData)
+		if err != nil {
+			return fmt.Errorf("cannot import %s inbound '%s': %v", inboundData.Protocol, inboundData.Remark, err)
+		}
+		finalSettings = settings
 	} else {
 		var settingsMap map[string]interface{}
 		if strings.TrimSpace(inboundData.OriginalSettings) != "" {
@@ -261,29 +270,11 @@ func (c *ThreeXUIClient) UpdateInbound(inboundID int, inboundData models.Inbound
 
 		var clientSettings []map[string]interface{}
 		for _, clientDetail := range inboundData.Clients {
-			clientSetting := map[string]interface{}{
-				"id":         clientDetail.ClientID,
-				"email":      clientDetail.ClientEmail,
-				"enable":     clientDetail.ClientEnable,
-				"totalGB":    clientDetail.ClientTotalGB,
-				"expiryTime": clientDetail.ClientExpiryTime,
-				"limitIp":    clientDetail.ClientLimitIP,
-				"flow":       clientDetail.ClientFlow,
-				"subId":      clientDetail.ClientSubID,
-				"tgId":       clientDetail.ClientTgID,
-				"reset":      clientDetail.ClientReset,
-			}
-			clientSettings = append(clientSettings, clientSetting)
+			clientSettings = append(clientSettings, ClientMap(clientDetail))
 		}
 
 		settingsMap["clients"] = clientSettings
-		if inboundData.Protocol == "shadowsocks" {
-			settingsMap["method"] = "aes-128-gcm"
-			settingsMap["password"] = "password"
-		} else if inboundData.Protocol == "socks" {
-			settingsMap["accounts"] = clientSettings
-			delete(settingsMap, "clients")
-		} else {
+		if inboundData.Protocol == "vless" {
 			settingsMap["decryption"] = "none"
 		}
 		settingsBytes, err := json.Marshal(settingsMap)
@@ -330,6 +321,24 @@ func (c *ThreeXUIClient) UpdateInbound(inboundID int, inboundData models.Inbound
 	return nil
 }
 
+// ClientDetailsFromSetting converts a client of an inbound's settings to its export form.
+func ClientDetailsFromSetting(client models.ClientSetting) models.ClientDetails {
+	return models.ClientDetails{
+		ClientEmail:      client.Email,
+		ClientID:         client.ID,
+		ClientEnable:     client.Enable,
+		ClientLimitIP:    client.LimitIP,
+		ClientTotalGB:    client.TotalGB,
+		ClientExpiryTime: client.ExpiryTime,
+		ClientFlow:       client.Flow,
+		ClientSubID:      client.SubID,
+		ClientTgID:       client.TgID,
+		ClientReset:      client.Reset,
+		ClientPassword:   client.Password,
+		ClientMethod:     client.Method,
+	}
+}
+
 // ExtractClientsFromInbounds processes the raw inbound list and enriches it with client data.
 func (c *ThreeXUIClient) ExtractClientsFromInbounds(inbounds []models.Inbound) ([]models.InboundData, int, error) {
 	var allInboundData []models.InboundData
@@ -364,24 +373,18 @@ func (c *ThreeXUIClient) ExtractClientsFromInbounds(inbounds []models.Inbound) (
 		fmt.Printf("\n→ Processing inbound: %s (%s:%d)\n", inbound.Remark, inbound.Protocol, inbound.Port)
 		fmt.Printf(" Number of clients: %d\n", len(settings.Clients))
 		totalUserCount += len(settings.Clients)
+		if isAccountProtocol(inbound.Protocol) && len(settings.Accounts) > 0 {
+			fmt.Printf(" Number of accounts: %d\n", len(settings.Accounts))
+			inboundData.Accounts = settings.Accounts
+		}
 		for _, client := range settings.Clients {
-			clientDetails := models.ClientDetails{
-				ClientEmail:      client.Email,
-				ClientID:         client.ID,
-				ClientEnable:     client.Enable,
-				ClientLimitIP:    client.LimitIP,
-				ClientTotalGB:    client.TotalGB,
-				ClientExpiryTime: client.ExpiryTime,
-				ClientFlow:       client.Flow,
-				ClientSubID:      client.SubID,
-				ClientTgID:       client.TgID,
-				ClientReset:      client.Reset,
-			}
+			clientDetails := ClientDetailsFromSetting(client)
 			if client.Email != "" {
 				traffic, err := c.GetClientTraffic(client.Email)
 				if err == nil && traffic != nil {
 					trafficUsed := traffic.Up + traffic.Down
 					clientDetails.TrafficUsed = trafficUsed
+					clientDetails.TrafficUp, clientDetails.TrafficDown = traffic.Up, traffic.Down
 					if client.TotalGB > 0 {
 						remaining := client.TotalGB - trafficUsed
 						if remaining < 0 {
@@ -0,0 +1,127 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"panels_user_manager/pkg/models"
+)
+
+// ClientKey returns the identifier 3X-UI uses for a client in client-level endpoints:
+// the password for trojan, the email for shadowsocks and the UUID for everything else.
+func ClientKey(protocol string, client map[string]interface{}) string {
+	field := "id"
+	switch protocol {
+	case "trojan":
+		field = "password"
+	case "shadowsocks":
+		field = "email"
+	}
+	key, _ := client[field].(string)
+	return key
+}
+
+// ClientMap builds the raw client object sent to 3X-UI for an exported client.
+func ClientMap(client models.ClientDetails) map[string]interface{} {
+	clientSetting := map[string]interface{}{
+		"id":         client.ClientID,
+		"email":      client.ClientEmail,
+		"enable":     client.ClientEnable,
+		"totalGB":    client.ClientTotalGB,
+		"expiryTime": client.ClientExpiryTime,
+		"limitIp":    client.ClientLimitIP,
+		"flow":       client.ClientFlow,
+		"subId":      client.ClientSubID,
+		"tgId":       client.ClientTgID,
+		"reset":      client.ClientReset,
+	}
+	if client.ClientPassword != "" {
+		clientSetting["password"] = client.ClientPassword
+	}
+	if client.ClientMethod != "" {
+		clientSetting["method"] = client.ClientMethod
+	}
+	return clientSetting
+}
+
+// AddClients adds clients to an existing inbound without touching its other clients.
+func (c *ThreeXUIClient) AddClients(inboundID int, clients []map[string]interface{}) error {
+	settings, err := json.Marshal(map[string]interface{}{"clients": clients})
+	if err != nil {
+		return fmt.Errorf("error marshalling client settings: %v", err)
+	}
+	payloadBytes, err := json.Marshal(map[string]interface{}{"id": inboundID, "settings": string(settings)})
+	if err != nil {
+		return fmt.Errorf("error marshalling add client payload: %v", err)
+	}
+	requestURL := fmt.Sprintf("%s/panel/api/inbounds/addClient", c.BaseURL)
+	return c.postClientRequest(requestURL, payloadBytes)
+}
+
+// UpdateClient replaces a single client of an inbound without touching the other clients.
+// client is the raw client object from the inbound settings, so fields unknown to this tool are kept.
+func (c *ThreeXUIClient) UpdateClient(inboundID int, clientKey string, client map[string]interface{}) error {
+	settings, err := json.Marshal(map[string]interface{}{"clients": []map[string]interface{}{client}})
+	if err != nil {
+		return fmt.Errorf("error marshalling client settings: %v", err)
+	}
+	payloadBytes, err := json.Marshal(map[string]interface{}{"id": inboundID, "settings": string(settings)})
+	if err != nil {
+		return fmt.Errorf("error marshalling update client payload: %v", err)
+	}
+	requestURL := fmt.Sprintf("%s/panel/api/inbounds/updateClient/%s", c.BaseURL, url.PathEscape(clientKey))
+	return c.postClientRequest(requestURL, payloadBytes)
+}
+
+// DeleteClient removes a single client from an inbound. clientKey is the value returned by ClientKey.
+func (c *ThreeXUIClient) DeleteClient(inboundID int, clientKey string) error {
+	requestURL := fmt.Sprintf("%s/panel/api/inbounds/%d/delClient/%s", c.BaseURL, inboundID, url.PathEscape(clientKey))
+	return c.postClientRequest(requestURL, nil)
+}
+
+// ResetClientTraffic sets the up/down counters of a client back to zero.
+func (c *ThreeXUIClient) ResetClientTraffic(inboundID int, email string) error {
+	requestURL := fmt.Sprintf("%s/panel/api/inbounds/%d/resetClientTraffic/%s", c.BaseURL, inboundID, url.PathEscape(email))
+	return c.postClientRequest(requestURL, nil)
+}
+
+// SetClientTraffic sets the up/down counters of a client. Panels older than the
+// updateClientTraffic endpoint answer 404; RestoreTrafficInDB covers those.
+func (c *ThreeXUIClient) SetClientTraffic(email string, up, down int64) error {
+	payloadBytes, err := json.Marshal(map[string]int64{"upload": up, "download": down})
+	if err != nil {
+		return fmt.Errorf("error marshalling client traffic payload: %v", err)
+	}
+	requestURL := fmt.Sprintf("%s/panel/api/inbounds/updateClientTraffic/%s", c.BaseURL, url.PathEscape(email))
+	return c.postClientRequest(requestURL, payloadBytes)
+}
+
+// postClientRequest sends a client-level request and checks both the HTTP status and the API result.
+func (c *ThreeXUIClient) postClientRequest(requestURL string, payloadBytes []byte) error {
+	req, err := http.NewRequest("POST", requestURL, bytes.NewBuffer(payloadBytes))
+	if err != nil {
+		return fmt.Errorf("error creating request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making API request: %v", err)
+	}
+	defer resp.Body.Close()
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("server returned status %d: %s", resp.StatusCode, string(bodyBytes))
+	}
+	var apiResp models.APIResponse
+	if err := json.Unmarshal(bodyBytes, &apiResp); err != nil {
+		return fmt.Errorf("error parsing response: %v", err)
+	}
+	if !apiResp.Success {
+		return fmt.Errorf("API error: %s", apiResp.Msg)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// WireGuardPeer is a peer of a 3X-UI wireguard inbound.
+type WireGuardPeer struct {
+	PrivateKey   string   `json:"privateKey"`
+	PublicKey    string   `json:"publicKey"`
+	PreSharedKey string   `json:"psk"`
+	AllowedIPs   []string `json:"allowedIPs"`
+	KeepAlive    int      `json:"keepAlive"`
+}
+
+// WireGuardSettings is the part of a wireguard inbound's settings needed to build client configs.
+type WireGuardSettings struct {
+	MTU   int             `json:"mtu"`
+	Peers []WireGuardPeer `json:"peers"`
+}
+
+// wireGuardKeyField returns the settings field holding the server private key. Current 3X-UI
+// versions use secretKey, older exports of this tool wrote privateKey.
+func wireGuardKeyField(settings map[string]interface{}) string {
+	if _, ok := settings["secretKey"]; ok {
+		return "secretKey"
+	}
+	if _, ok := settings["privateKey"]; ok {
+		return "privateKey"
+	}
+	return "secretKey"
+}
+
+// parseWireGuardSettings decodes raw settings, treating empty settings as an inbound without peers.
+func parseWireGuardSettings(raw string) (map[string]interface{}, error) {
+	settings := make(map[string]interface{})
+	if strings.TrimSpace(raw) != "" {
+		if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+			return nil, err
+		}
+	}
+	if settings["peers"] == nil {
+		settings["peers"] = []interface{}{}
+	}
+	return settings, nil
+}
+
+// setWireGuardKey stores a new server key pair in settings and returns the public key.
+func setWireGuardKey(settings map[string]interface{}) (string, error) {
+	privateKey, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate wireguard private key: %v", err)
+	}
+	settings[wireGuardKeyField(settings)] = privateKey.String()
+	if _, ok := settings["publicKey"]; ok {
+		settings["publicKey"] = privateKey.PublicKey().String()
+	}
+	return privateKey.PublicKey().String(), nil
+}
+
+// EnsureWireGuardKey returns the settings unchanged when they contain a server key, or with a
+// newly generated key otherwise. generated reports whether a key was created.
+func EnsureWireGuardKey(raw string) (string, bool, error) {
+	settings, err := parseWireGuardSettings(raw)
+	if err != nil {
+		return "", false, err
+	}
+	if key, _ := settings[wireGuardKeyField(settings)].(string); key != "" {
+		return raw, false, nil
+	}
+	if _, err := setWireGuardKey(settings); err != nil {
+		return "", false, err
+	}
+	out, err := json.Marshal(settings)
+	return string(out), true, err
+}
+
+// RotateWireGuardKey replaces the server key pair and returns the new settings and public key.
+// Peers are kept; their configs must be re-issued with the new public key.
+func RotateWireGuardKey(raw string) (string, string, error) {
+	settings, err := parseWireGuardSettings(raw)
+	if err != nil {
+		return "", "", err
+	}
+	publicKey, err := setWireGuardKey(settings)
+	if err != nil {
+		return "", "", err
+	}
+	out, err := json.Marshal(settings)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal new wireguard settings: %v", err)
+	}
+	return string(out), publicKey, nil
+}
+
+// ParseWireGuardSettings reads the MTU and peers of a wireguard inbound.
+func ParseWireGuardSettings(raw string) (WireGuardSettings, error) {
+	var settings WireGuardSettings
+	if strings.TrimSpace(raw) == "" {
+		return settings, nil
+	}
+	err := json.Unmarshal([]byte(raw), &settings)
+	return settings, err
+}
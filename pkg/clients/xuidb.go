@@ -0,0 +1,49 @@
+package clients
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "modernc.org/sqlite"
+
+	"panels_user_manager/pkg/models"
+)
+
+// RestoreTrafficInDB writes up/down counters straight into the client_traffics table of a
+// 3X-UI database (usually /etc/x-ui/x-ui.db). The panel should be stopped while it runs,
+// otherwise it may overwrite the counters with its in-memory values. traffic is keyed by
+// client email; the emails that were found and updated are returned.
+func RestoreTrafficInDB(dbPath string, traffic map[string]models.ClientTraffic) ([]string, error) {
+	db, err := sql.Open("sqlite", "file:"+dbPath+"?mode=rw&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %v", dbPath, err)
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("could not start transaction on %s: %v", dbPath, err)
+	}
+	stmt, err := tx.Prepare("UPDATE client_traffics SET up = ?, down = ? WHERE email = ?")
+	if err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("%s does not look like a 3X-UI database: %v", dbPath, err)
+	}
+	defer stmt.Close()
+
+	var updated []string
+	for email, t := range traffic {
+		res, err := stmt.Exec(t.Up, t.Down, email)
+		if err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("could not update traffic of %s: %v", email, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			updated = append(updated, email)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("could not commit traffic changes: %v", err)
+	}
+	return updated, nil
+}
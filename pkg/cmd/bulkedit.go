@@ -0,0 +1,316 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"panels_user_manager/pkg/bulk"
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// RunPasarGuardBulkEdit extends expiry, adds traffic or enables/disables every PasarGuard user
+// matching a set of filters. The changes are previewed and confirmed before they are sent.
+func RunPasarGuardBulkEdit(client *clients.PasarGuardClient) {
+	fmt.Println("\n" + utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightCyan+"✏️ BULK EDIT (PasarGuard)"+utils.ColorReset, 70) + utils.ColorBrightMagenta + "║" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+
+	users, err := client.GetAllUsers()
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Error fetching users: %v", err))
+		return
+	}
+	filter, ok := promptUserFilter(client)
+	if !ok {
+		return
+	}
+	selected := bulk.FilterUsers(users, filter)
+	if len(selected) == 0 {
+		utils.PrintWarning("No users matched the filters")
+		return
+	}
+	fmt.Printf(" "+utils.ColorGreen+"✓ %d of %d user(s) matched"+utils.ColorReset+"\n", len(selected), len(users))
+
+	edit, ok := promptUserEdit()
+	if !ok {
+		return
+	}
+	changes, unaffected := bulk.PlanEdits(selected, edit, time.Now())
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Preview:" + utils.ColorReset)
+	bulk.PrintChanges(changes, unaffected)
+	if len(changes) == 0 {
+		utils.PrintInfo("Nothing to change")
+		return
+	}
+	if !confirmBulk(fmt.Sprintf("Apply to these %d user(s)? Type 'yes' to confirm", len(changes))) {
+		utils.PrintInfo("Nothing was changed")
+		return
+	}
+
+	failed := bulk.ApplyChanges(client, changes)
+	fmt.Printf("\n "+utils.ColorBrightGreen+"✓ %d user(s) updated"+utils.ColorReset+"\n", len(changes)-len(failed))
+	if len(failed) > 0 {
+		fmt.Printf(" "+utils.ColorRed+"✗ %d user(s) failed"+utils.ColorReset+"\n", len(failed))
+	}
+}
+
+// promptUserFilter asks for the filters of a bulk edit; every one can be skipped with Enter.
+func promptUserFilter(client *clients.PasarGuardClient) (bulk.UserFilter, bool) {
+	var filter bulk.UserFilter
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Filters (Enter to skip):" + utils.ColorReset)
+
+	if input := PromptForInputStyled("Username pattern (regular expression)", " ➜", utils.ColorBrightYellow); input != "" {
+		re, err := regexp.Compile(input)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Invalid pattern: %v", err))
+			return filter, false
+		}
+		filter.Username = re
+	}
+	if strings.ToLower(PromptForInputStyled("Filter by group? (y/N)", " ➜", utils.ColorBrightYellow)) == "y" {
+		groupID, ok := promptGroupID(client)
+		if !ok {
+			return filter, false
+		}
+		filter.GroupID = groupID
+	}
+	switch input := strings.ToLower(PromptForInputStyled("Status (active, disabled, limited, expired, on_hold)", " ➜", utils.ColorBrightYellow)); input {
+	case "":
+	case "active", "disabled", "limited", "expired", "on_hold":
+		filter.Status = input
+	default:
+		utils.PrintError(fmt.Sprintf("Unknown status '%s'", input))
+		return filter, false
+	}
+	if input := PromptForInputStyled("Expiring within N days (negative: expired in the last N days)", " ➜", utils.ColorBrightYellow); input != "" {
+		days, err := strconv.Atoi(input)
+		if err != nil || days == 0 {
+			utils.PrintError(fmt.Sprintf("Invalid number of days '%s'", input))
+			return filter, false
+		}
+		now := time.Now().Unix()
+		filter.ExpiryFrom, filter.ExpiryTo = now, now+int64(days)*86400
+		if days < 0 {
+			filter.ExpiryFrom, filter.ExpiryTo = filter.ExpiryTo, now
+		}
+	}
+	if input := PromptForInputStyled("Usage above percent of quota", " ➜", utils.ColorBrightYellow); input != "" {
+		percent, err := strconv.ParseFloat(input, 64)
+		if err != nil || percent <= 0 {
+			utils.PrintError(fmt.Sprintf("Invalid percentage '%s'", input))
+			return filter, false
+		}
+		filter.UsageAbove = percent
+	}
+	filter.NoteContains = PromptForInputStyled("Note contains", " ➜", utils.ColorBrightYellow)
+	return filter, true
+}
+
+// promptUserEdit asks which change a bulk edit applies.
+func promptUserEdit() (bulk.UserEdit, bool) {
+	var edit bulk.UserEdit
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Change:" + utils.ColorReset)
+	fmt.Println(" \t[1] Extend expiry by N days")
+	fmt.Println(" \t[2] Add traffic (GB)")
+	fmt.Println(" \t[3] Enable users")
+	fmt.Println(" \t[4] Disable users")
+	switch choice := PromptForInputStyled("Select", " ➜", utils.ColorBrightYellow); choice {
+	case "1":
+		var ok bool
+		if edit.ExtendDays, edit.FromToday, ok = promptExtendDays(); !ok {
+			return edit, false
+		}
+	case "2":
+		var ok bool
+		if edit.AddTraffic, ok = promptTrafficDelta(); !ok {
+			return edit, false
+		}
+	case "3", "4":
+		enable := choice == "3"
+		edit.SetEnable = &enable
+	default:
+		utils.PrintError("Invalid option")
+		return edit, false
+	}
+	return edit, true
+}
+
+// RunThreeXUIBulkEdit changes expiry, quota, traffic, status or IP limit of every 3X-UI client
+// matching a set of filters, one client at a time. The changes are previewed and confirmed first.
+func RunThreeXUIBulkEdit(client *clients.ThreeXUIClient) {
+	fmt.Println("\n" + utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightCyan+"✏️ BULK EDIT (3X-UI)"+utils.ColorReset, 70) + utils.ColorBrightMagenta + "║" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+
+	inbounds, err := client.GetAllInbounds()
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Error fetching inbounds: %v", err))
+		return
+	}
+	filter, ok := promptClientFilter(inbounds)
+	if !ok {
+		return
+	}
+	var scoped []models.Inbound
+	for _, inbound := range inbounds {
+		if len(filter.InboundIDs) == 0 || containsID(filter.InboundIDs, inbound.ID) {
+			scoped = append(scoped, inbound)
+		}
+	}
+	inboundsData, _, err := client.ExtractClientsFromInbounds(scoped)
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Error extracting clients: %v", err))
+		return
+	}
+	now := time.Now()
+	selected := bulk.SelectClients(scoped, inboundsData, filter, now)
+	if len(selected) == 0 {
+		utils.PrintWarning("No clients matched the filters")
+		return
+	}
+	fmt.Printf("\n "+utils.ColorGreen+"✓ %d client(s) matched"+utils.ColorReset+"\n", len(selected))
+
+	edit, ok := promptClientEdit()
+	if !ok {
+		return
+	}
+	changes, unaffected := bulk.PlanClientEdits(selected, edit, now)
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Preview:" + utils.ColorReset)
+	bulk.PrintClientChanges(changes, unaffected)
+	if len(changes) == 0 {
+		utils.PrintInfo("Nothing to change")
+		return
+	}
+	if !confirmBulk(fmt.Sprintf("Apply to these %d client(s)? Type 'yes' to confirm", len(changes))) {
+		utils.PrintInfo("Nothing was changed")
+		return
+	}
+
+	failed := bulk.ApplyClientChanges(client, changes)
+	fmt.Printf("\n "+utils.ColorBrightGreen+"✓ %d client(s) updated"+utils.ColorReset+"\n", len(changes)-len(failed))
+	if len(failed) > 0 {
+		fmt.Printf(" "+utils.ColorRed+"✗ %d client(s) failed"+utils.ColorReset+"\n", len(failed))
+	}
+}
+
+// promptClientFilter asks for the filters of a 3X-UI bulk edit; every one can be skipped with Enter.
+func promptClientFilter(inbounds []models.Inbound) (bulk.ClientFilter, bool) {
+	var filter bulk.ClientFilter
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Inbounds:" + utils.ColorReset)
+	for _, inbound := range inbounds {
+		fmt.Printf(" \t[%d] %s (%s:%d)\n", inbound.ID, inbound.Remark, inbound.Protocol, inbound.Port)
+	}
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Filters (Enter to skip):" + utils.ColorReset)
+	for _, part := range strings.Split(PromptForInputStyled("Inbound IDs (comma-separated)", " ➜", utils.ColorBrightYellow), ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Invalid inbound ID '%s'", part))
+			return filter, false
+		}
+		filter.InboundIDs = append(filter.InboundIDs, id)
+	}
+	if input := PromptForInputStyled("Email pattern (regular expression)", " ➜", utils.ColorBrightYellow); input != "" {
+		re, err := regexp.Compile(input)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Invalid pattern: %v", err))
+			return filter, false
+		}
+		filter.Email = re
+	}
+	if input := PromptForInputStyled("Expiring within N days", " ➜", utils.ColorBrightYellow); input != "" {
+		days, err := strconv.Atoi(input)
+		if err != nil || days <= 0 {
+			utils.PrintError(fmt.Sprintf("Invalid number of days '%s'", input))
+			return filter, false
+		}
+		filter.ExpiringDays = days
+	}
+	if input := PromptForInputStyled("Usage above percent of quota", " ➜", utils.ColorBrightYellow); input != "" {
+		percent, err := strconv.ParseFloat(input, 64)
+		if err != nil || percent <= 0 {
+			utils.PrintError(fmt.Sprintf("Invalid percentage '%s'", input))
+			return filter, false
+		}
+		filter.UsageAbove = percent
+	}
+	return filter, true
+}
+
+// promptClientEdit asks which change a 3X-UI bulk edit applies.
+func promptClientEdit() (bulk.ClientEdit, bool) {
+	var edit bulk.ClientEdit
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Change:" + utils.ColorReset)
+	fmt.Println(" \t[1] Extend expiry by N days")
+	fmt.Println(" \t[2] Add quota (GB)")
+	fmt.Println(" \t[3] Reset used traffic")
+	fmt.Println(" \t[4] Enable clients")
+	fmt.Println(" \t[5] Disable clients")
+	fmt.Println(" \t[6] Change IP limit")
+	switch choice := PromptForInputStyled("Select", " ➜", utils.ColorBrightYellow); choice {
+	case "1":
+		var ok bool
+		if edit.ExtendDays, edit.FromToday, ok = promptExtendDays(); !ok {
+			return edit, false
+		}
+	case "2":
+		var ok bool
+		if edit.AddQuota, ok = promptTrafficDelta(); !ok {
+			return edit, false
+		}
+	case "3":
+		edit.ResetTraffic = true
+	case "4", "5":
+		enable := choice == "4"
+		edit.SetEnable = &enable
+	case "6":
+		limit, err := strconv.Atoi(PromptForInputStyled("New IP limit (0 for no limit)", " ➜", utils.ColorBrightYellow))
+		if err != nil || limit < 0 {
+			utils.PrintError("Invalid IP limit")
+			return edit, false
+		}
+		edit.SetLimitIP = &limit
+	default:
+		utils.PrintError("Invalid option")
+		return edit, false
+	}
+	return edit, true
+}
+
+// promptExtendDays asks how many days to add and whether expired users count from today.
+func promptExtendDays() (int, bool, bool) {
+	days, err := strconv.Atoi(PromptForInputStyled("Days to add (negative to shorten)", " ➜", utils.ColorBrightYellow))
+	if err != nil || days == 0 {
+		utils.PrintError("Invalid number of days")
+		return 0, false, false
+	}
+	fmt.Println(" \t[1] Add to the current expiry, also for expired users (default)")
+	fmt.Println(" \t[2] Count from today for users that already expired")
+	return days, PromptForInputStyled("Select [1]", " ➜", utils.ColorBrightYellow) == "2", true
+}
+
+// promptTrafficDelta asks for an amount of traffic in GB and returns it in bytes.
+func promptTrafficDelta() (int64, bool) {
+	gb, err := strconv.ParseFloat(PromptForInputStyled("GB to add (negative to take away)", " ➜", utils.ColorBrightYellow), 64)
+	if err != nil || gb == 0 {
+		utils.PrintError("Invalid amount")
+		return 0, false
+	}
+	return int64(gb * 1024 * 1024 * 1024), true
+}
+
+func containsID(ids []int, id int) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
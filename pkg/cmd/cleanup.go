@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"panels_user_manager/pkg/bulk"
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/importers"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/storage"
+	"panels_user_manager/pkg/utils"
+)
+
+// RunPasarGuardCleanup deletes PasarGuard users picked from an import journal or file, by
+// username pattern or by group. The users are listed and confirmed before anything is deleted.
+func RunPasarGuardCleanup(client *clients.PasarGuardClient) {
+	fmt.Println("\n" + utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightCyan+"🧹 DELETE USERS (PasarGuard)"+utils.ColorReset, 70) + utils.ColorBrightMagenta + "║" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+
+	users, err := client.GetAllUsers()
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Error fetching users: %v", err))
+		return
+	}
+
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Delete:" + utils.ColorReset)
+	fmt.Println(" \t[1] Users created by an import (journal), or listed in an export or text file")
+	fmt.Println(" \t[2] Users whose username matches a pattern")
+	fmt.Println(" \t[3] Users in a group")
+	var selected []models.PasarGuardUser
+	switch PromptForInputStyled("Select", " ➜", utils.ColorBrightYellow) {
+	case "1":
+		path := PromptForInputStyled("Path to the journal, export file or username list", " ➜", utils.ColorBrightYellow)
+		listed, err := loadListedUsers(path)
+		if err != nil {
+			utils.PrintError(err.Error())
+			return
+		}
+		var missing []string
+		selected, missing = bulk.MatchListed(users, listed)
+		if len(missing) > 0 {
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ %d listed user(s) are not on the panel\n"+utils.ColorReset, len(missing))
+			utils.VerboseLog("Not on the panel: %s", strings.Join(missing, ", "))
+		}
+	case "2":
+		re, err := regexp.Compile(PromptForInputStyled("Username pattern (regular expression, e.g. _[0-9]+$)", " ➜", utils.ColorBrightYellow))
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Invalid pattern: %v", err))
+			return
+		}
+		selected = bulk.FilterUsers(users, bulk.UserFilter{Username: re})
+	case "3":
+		groupID, ok := promptGroupID(client)
+		if !ok {
+			return
+		}
+		selected = bulk.FilterUsers(users, bulk.UserFilter{GroupID: groupID})
+	default:
+		utils.PrintError("Invalid option")
+		return
+	}
+
+	if len(selected) == 0 {
+		utils.PrintWarning("No users matched, nothing to delete")
+		return
+	}
+	bulk.PrintUsers(selected)
+	if !confirmBulk(fmt.Sprintf("Delete these %d user(s)? This cannot be undone. Type 'yes' to confirm", len(selected))) {
+		utils.PrintInfo("Nothing was deleted")
+		return
+	}
+
+	failed := bulk.DeleteUsers(client, selected)
+	fmt.Printf("\n "+utils.ColorBrightGreen+"✓ %d user(s) deleted"+utils.ColorReset+"\n", len(selected)-len(failed))
+	if len(failed) > 0 {
+		fmt.Printf(" "+utils.ColorRed+"✗ %d user(s) failed"+utils.ColorReset+"\n", len(failed))
+	}
+}
+
+// loadListedUsers reads the users named by an import journal (only the ones the import created;
+// updated users existed before it), a PasarGuard export file, or a text file with one username
+// per line.
+func loadListedUsers(path string) ([]bulk.ListedUser, error) {
+	if journal, err := importers.LoadImportJournal(path); err == nil {
+		var listed []bulk.ListedUser
+		kept := 0
+		for _, e := range journal.Entries {
+			if e.Action != importers.JournalCreated {
+				kept++
+				continue
+			}
+			listed = append(listed, bulk.ListedUser{Username: e.Username, UUID: e.UUID})
+		}
+		fmt.Printf(" "+utils.ColorGreen+"✓ Journal of %s (%s): %d created user(s)"+utils.ColorReset+"\n", journal.SourceFile, journal.ImportedAt, len(listed))
+		if kept > 0 {
+			fmt.Printf(" "+utils.ColorDim+"  %d user(s) the import only updated are kept"+utils.ColorReset+"\n", kept)
+		}
+		return listed, nil
+	}
+
+	if opts, err := importers.DecryptionOptions(path); err == nil {
+		if records, err := storage.OpenRecords[models.PasarGuardUser](path, opts, storage.KindUsers); err == nil {
+			defer records.Close()
+			var listed []bulk.ListedUser
+			for records.More() {
+				user, err := records.Next()
+				if err != nil {
+					return nil, fmt.Errorf("error reading '%s': %v", path, err)
+				}
+				listed = append(listed, bulk.ListedUser{Username: user.Username, UUID: user.UUID})
+			}
+			fmt.Printf(" "+utils.ColorGreen+"✓ Export file lists %d user(s)"+utils.ColorReset+"\n", len(listed))
+			return listed, nil
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading '%s': %v", path, err)
+	}
+	defer file.Close()
+	var listed []bulk.ListedUser
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if name := strings.TrimSpace(scanner.Text()); name != "" && !strings.HasPrefix(name, "#") {
+			listed = append(listed, bulk.ListedUser{Username: name})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading '%s': %v", path, err)
+	}
+	fmt.Printf(" "+utils.ColorGreen+"✓ File lists %d username(s)"+utils.ColorReset+"\n", len(listed))
+	return listed, nil
+}
+
+// promptGroupID shows the panel's groups and asks for one by ID or name.
+func promptGroupID(client *clients.PasarGuardClient) (int, bool) {
+	groups, err := client.GetAllGroups()
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Error fetching groups: %v", err))
+		return 0, false
+	}
+	for _, g := range groups {
+		fmt.Printf(" \t[%d] %s\n", g.ID, g.Name)
+	}
+	input := PromptForInputStyled("Group ID or name", " ➜", utils.ColorBrightYellow)
+	for _, g := range groups {
+		if strconv.Itoa(g.ID) == input || strings.EqualFold(g.Name, input) {
+			return g.ID, true
+		}
+	}
+	utils.PrintError(fmt.Sprintf("Group '%s' not found", input))
+	return 0, false
+}
+
+// confirmBulk asks for an explicit "yes" before a bulk change is applied.
+func confirmBulk(label string) bool {
+	return strings.ToLower(PromptForInputStyled(label, "\n ➜", utils.ColorBrightRed)) == "yes"
+}
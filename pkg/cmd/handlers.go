@@ -3,12 +3,12 @@ package cmd
 import (
 	"bufio"
 	"fmt"
-	"os"
 	"strings"
 
 	"panels_user_manager/pkg/clients"
 	"panels_user_manager/pkg/exporters"
 	"panels_user_manager/pkg/importers"
+	"panels_user_manager/pkg/storage"
 	"panels_user_manager/pkg/utils"
 )
 
@@ -36,13 +36,22 @@ func ShowMenu() {
 	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 	fmt.Println()
 
+	fmt.Println(utils.ColorBrightGreen + "  🧰 TOOLS" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  ┌─────────────────────────────────────────────────────────────┐" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[3] Tools" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + "     " + utils.ColorDim + "├─ Diff export files and live panels" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + "     " + utils.ColorDim + "├─ Continuous 3X-UI → PasarGuard sync" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + "     " + utils.ColorDim + "└─ Cross-panel traffic reconciliation" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
+	fmt.Println()
+
 	fmt.Println(utils.ColorBrightRed + "  🚪 APPLICATION CONTROL" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightBlue + "  ┌─────────────────────────────────────────────────────────────┐" + utils.ColorReset)
-	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[3] Exit Application" + utils.ColorReset + utils.ColorDim + " (close and return to system)" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[4] Exit Application" + utils.ColorReset + utils.ColorDim + " (close and return to system)" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 	fmt.Println()
 
-	fmt.Print(utils.ColorBrightMagenta + "  ➜ Select an option (1-3): " + utils.ColorReset)
+	fmt.Print(utils.ColorBrightMagenta + "  ➜ Select an option (1-4): " + utils.ColorReset)
 }
 
 // Show3XUIMenu displays the 3X-UI panel menu.
@@ -72,13 +81,20 @@ func Show3XUIMenu() {
 	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 	fmt.Println()
 
+	fmt.Println(utils.ColorBrightCyan + "  🧹 MAINTENANCE" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  ┌─────────────────────────────────────────────────────────────┐" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[4] Bulk edit clients" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + "     " + utils.ColorDim + "└─ Extend expiry, add quota, reset traffic, toggle or IP limit" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
+	fmt.Println()
+
 	fmt.Println(utils.ColorBrightRed + "  🔙 NAVIGATION" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightBlue + "  ┌─────────────────────────────────────────────────────────────┐" + utils.ColorReset)
-	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[4] Return to main menu" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[5] Return to main menu" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 	fmt.Println()
 
-	fmt.Print(utils.ColorBrightMagenta + "  ➜ Select an option (1-4): " + utils.ColorReset)
+	fmt.Print(utils.ColorBrightMagenta + "  ➜ Select an option (1-5): " + utils.ColorReset)
 }
 
 // ShowPasarGuardMenu displays the PasarGuard panel menu.
@@ -105,13 +121,23 @@ func ShowPasarGuardMenu() {
 	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 	fmt.Println()
 
+	fmt.Println(utils.ColorBrightCyan + "  🧹 MAINTENANCE" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  ┌─────────────────────────────────────────────────────────────┐" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[3] Delete users" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + "     " + utils.ColorDim + "└─ By import journal or file, username pattern or group" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[4] Bulk edit users" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + "     " + utils.ColorDim + "└─ Extend expiry, add traffic, enable or disable" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
+	fmt.Println()
+
 	fmt.Println(utils.ColorBrightRed + "  🔙 NAVIGATION" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightBlue + "  ┌─────────────────────────────────────────────────────────────┐" + utils.ColorReset)
-	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[3] Return to main menu" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[5] Return to main menu" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 	fmt.Println()
 
-	fmt.Print(utils.ColorBrightMagenta + "  ➜ Select an option (1-3): " + utils.ColorReset)
+	fmt.Print(utils.ColorBrightMagenta + "  ➜ Select an option (1-5): " + utils.ColorReset)
 }
 
 // GetLoginSettings prompts the user for panel connection details.
@@ -123,7 +149,7 @@ func GetLoginSettings() (string, string, string) {
 	baseURL := PromptForInputStyled("Panel Address", " │ (e.g., http://127.0.0.1:2053)", utils.ColorBrightGreen)
 	fmt.Println("\n " + utils.ColorBrightBlue + "┌─ Authentication Credentials" + utils.ColorReset)
 	username := PromptForInputStyled("Username", " │", utils.ColorBrightYellow)
-	password := PromptForInputStyled("Password", " └", utils.ColorBrightRed)
+	password := PromptForSecretStyled("Password", " └", utils.ColorBrightRed)
 	fmt.Println()
 	fmt.Println(" " + utils.ColorBrightCyan + "┌─ Connection Summary" + utils.ColorReset)
 	fmt.Printf(" │ "+utils.ColorGreen+"🔗 URL: "+utils.ColorReset+"%s\n", baseURL)
@@ -133,12 +159,13 @@ func GetLoginSettings() (string, string, string) {
 }
 
 // GetExportSettings prompts for all details required for exporting 3X-UI.
-func GetExportSettings() (string, string, string, string) {
+func GetExportSettings() (string, string, string, string, storage.Options) {
 	baseURL, username, password := GetLoginSettings()
 	fmt.Println("\n" + utils.ColorBrightGreen + strings.Repeat("═", 72) + utils.ColorReset)
 	fmt.Println(utils.ColorBrightGreen + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightYellow+"📤 EXPORT CONFIGURATION"+utils.ColorReset, 70) + utils.ColorBrightGreen + "║" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightGreen + strings.Repeat("═", 72) + utils.ColorReset)
-	defaultFilename := "3xui_users_data.json"
+	opts := GetOutputOptions()
+	defaultFilename := "3xui_users_data" + opts.Extension()
 	fmt.Printf("\n " + utils.ColorBrightCyan + "📁 Output File Configuration\n" + utils.ColorReset)
 	fmt.Printf(" │ "+utils.ColorCyan+"Default filename: "+utils.ColorReset+"%s\n", defaultFilename)
 	fmt.Printf(" │\n")
@@ -147,16 +174,17 @@ func GetExportSettings() (string, string, string, string) {
 		filename = defaultFilename
 		fmt.Printf(" "+utils.ColorGreen+"✓ Using default: "+utils.ColorReset+"%s\n", filename)
 	}
-	return baseURL, username, password, filename
+	return baseURL, username, password, filename, opts
 }
 
 // GetPasarGuardExportSettings prompts for all details required for exporting PasarGuard users.
-func GetPasarGuardExportSettings() (string, string, string, string) {
+func GetPasarGuardExportSettings() (string, string, string, string, storage.Options) {
 	baseURL, username, password := GetLoginSettings()
 	fmt.Println("\n" + utils.ColorBrightGreen + strings.Repeat("═", 72) + utils.ColorReset)
 	fmt.Println(utils.ColorBrightGreen + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightYellow+"📤 EXPORT CONFIGURATION (PasarGuard)"+utils.ColorReset, 70) + utils.ColorBrightGreen + "║" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightGreen + strings.Repeat("═", 72) + utils.ColorReset)
-	defaultFilename := "pasarguard_users_data.json"
+	opts := GetOutputOptions()
+	defaultFilename := "pasarguard_users_data" + opts.Extension()
 	fmt.Printf("\n " + utils.ColorBrightCyan + "📁 Output File Configuration\n" + utils.ColorReset)
 	fmt.Printf(" │ "+utils.ColorCyan+"Default filename: "+utils.ColorReset+"%s\n", defaultFilename)
 	fmt.Printf(" │\n")
@@ -165,15 +193,87 @@ func GetPasarGuardExportSettings() (string, string, string, string) {
 		filename = defaultFilename
 		fmt.Printf(" "+utils.ColorGreen+"✓ Using default: "+utils.ColorReset+"%s\n", filename)
 	}
-	return baseURL, username, password, filename
+	return baseURL, username, password, filename, opts
+}
+
+// GetOutputOptions asks for the export file layout, compression and encryption.
+func GetOutputOptions() storage.Options {
+	fmt.Printf("\n " + utils.ColorBrightCyan + "🗜️ Export Format\n" + utils.ColorReset)
+	fmt.Println(" │ [1] JSON document (default)")
+	fmt.Println(" │ [2] JSON document, gzip-compressed")
+	fmt.Println(" │ [3] JSON document, zstd-compressed")
+	fmt.Println(" │ [4] Streaming NDJSON (one record per line), zstd-compressed")
+	fmt.Println(" │ [5] Streaming NDJSON (one record per line), uncompressed")
+	choice := PromptForInputStyled("Select export format (or press Enter for default)", " └", utils.ColorBrightMagenta)
+	var opts storage.Options
+	switch choice {
+	case "2":
+		opts.Compression = storage.CompressionGzip
+	case "3":
+		opts.Compression = storage.CompressionZstd
+	case "4":
+		opts.Streaming = true
+		opts.Compression = storage.CompressionZstd
+	case "5":
+		opts.Streaming = true
+	}
+	encOpts := GetEncryptionSettings()
+	opts.Passphrase = encOpts.Passphrase
+	opts.Recipients = encOpts.Recipients
+	return opts
+}
+
+// GetEncryptionSettings asks whether the export file should be encrypted and how.
+func GetEncryptionSettings() storage.Options {
+	fmt.Printf("\n " + utils.ColorBrightCyan + "🔒 Export Encryption\n" + utils.ColorReset)
+	fmt.Println(" │ [1] No encryption")
+	fmt.Println(" │ [2] Encrypt with a passphrase")
+	fmt.Println(" │ [3] Encrypt to age recipient key(s)")
+	choice := PromptForInputStyled("Select encryption mode (or press Enter for none)", " └", utils.ColorBrightMagenta)
+	switch choice {
+	case "2":
+		for {
+			passphrase := PromptForSecretStyled("Passphrase", " │", utils.ColorBrightRed)
+			if passphrase == "" {
+				fmt.Println(" " + utils.ColorBrightYellow + "⚠️ Passphrase cannot be empty" + utils.ColorReset)
+				continue
+			}
+			confirm := PromptForSecretStyled("Confirm passphrase", " └", utils.ColorBrightRed)
+			if confirm != passphrase {
+				fmt.Println(" " + utils.ColorBrightYellow + "⚠️ Passphrases do not match, try again" + utils.ColorReset)
+				continue
+			}
+			fmt.Println(" " + utils.ColorGreen + "✓ Export will be encrypted with a passphrase" + utils.ColorReset)
+			return storage.Options{Passphrase: passphrase}
+		}
+	case "3":
+		input := PromptForInputStyled("age recipient(s) (age1..., comma-separated)", " └", utils.ColorBrightYellow)
+		var recipients []string
+		for _, r := range strings.Split(input, ",") {
+			if r = strings.TrimSpace(r); r != "" {
+				recipients = append(recipients, r)
+			}
+		}
+		if len(recipients) == 0 {
+			fmt.Println(" " + utils.ColorBrightYellow + "⚠️ No recipients given, export will not be encrypted" + utils.ColorReset)
+			return storage.Options{}
+		}
+		fmt.Printf(" "+utils.ColorGreen+"✓ Export will be encrypted to %d recipient(s)\n"+utils.ColorReset, len(recipients))
+		return storage.Options{Recipients: recipients}
+	}
+	return storage.Options{}
 }
 
 // PromptForInputStyled displays a styled prompt and returns the user's input.
 func PromptForInputStyled(label, prefix, color string) string {
-	reader := bufio.NewReader(os.Stdin)
 	fmt.Printf("%s %s%s%s: ", prefix, color, label, utils.ColorReset)
-	input, _ := reader.ReadString('\n')
-	return strings.TrimSpace(input)
+	return utils.ReadLine()
+}
+
+// PromptForSecretStyled is PromptForInputStyled for passwords and keys: the input is not echoed.
+func PromptForSecretStyled(label, prefix, color string) string {
+	fmt.Printf("%s %s%s%s: ", prefix, color, label, utils.ColorReset)
+	return utils.ReadSecret()
 }
 
 // Handle3XUIMenu handles the 3X-UI panel menu operations.
@@ -184,13 +284,13 @@ func Handle3XUIMenu(reader *bufio.Reader) {
 		choice = strings.TrimSpace(choice)
 		switch choice {
 		case "1":
-			baseURL, username, password, filename := GetExportSettings()
-			RunExporter(baseURL, username, password, filename)
+			baseURL, username, password, filename, opts := GetExportSettings()
+			RunExporter(baseURL, username, password, filename, opts)
 			fmt.Println("\nPress Enter to return to the menu...")
 			reader.ReadString('\n')
 		case "2":
-			baseURL, username, password, filename := GetExportSettings()
-			RunUsersExporter(baseURL, username, password, filename)
+			baseURL, username, password, filename, opts := GetExportSettings()
+			RunUsersExporter(baseURL, username, password, filename, opts)
 			fmt.Println("\nPress Enter to return to the menu...")
 			reader.ReadString('\n')
 		case "3":
@@ -206,6 +306,18 @@ func Handle3XUIMenu(reader *bufio.Reader) {
 			fmt.Println("\nPress Enter to return to the menu...")
 			reader.ReadString('\n')
 		case "4":
+			baseURL, username, password := GetLoginSettings()
+			client := clients.NewThreeXUIClient(baseURL, username, password)
+			if err := client.Login(); err != nil {
+				fmt.Printf("\n✗ Login failed: %v\n", err)
+				fmt.Println("\nPress Enter to return to the menu...")
+				reader.ReadString('\n')
+				continue
+			}
+			RunThreeXUIBulkEdit(client)
+			fmt.Println("\nPress Enter to return to the menu...")
+			reader.ReadString('\n')
+		case "5":
 			return
 		default:
 			fmt.Println("Invalid option. Please try again.")
@@ -223,8 +335,8 @@ func HandlePasarGuardMenu(reader *bufio.Reader) {
 		choice = strings.TrimSpace(choice)
 		switch choice {
 		case "1":
-			baseURL, username, password, filename := GetPasarGuardExportSettings()
-			RunPasarGuardExporter(baseURL, username, password, filename)
+			baseURL, username, password, filename, opts := GetPasarGuardExportSettings()
+			RunPasarGuardExporter(baseURL, username, password, filename, opts)
 			fmt.Println("\nPress Enter to return to the menu...")
 			reader.ReadString('\n')
 		case "2":
@@ -240,6 +352,30 @@ func HandlePasarGuardMenu(reader *bufio.Reader) {
 			fmt.Println("\nPress Enter to return to the menu...")
 			reader.ReadString('\n')
 		case "3":
+			baseURL, username, password := GetLoginSettings()
+			client := clients.NewPasarGuardClient(baseURL, username, password)
+			if err := client.Login(); err != nil {
+				fmt.Printf("\n✗ Login failed: %v\n", err)
+				fmt.Println("\nPress Enter to return to the menu...")
+				reader.ReadString('\n')
+				continue
+			}
+			RunPasarGuardCleanup(client)
+			fmt.Println("\nPress Enter to return to the menu...")
+			reader.ReadString('\n')
+		case "4":
+			baseURL, username, password := GetLoginSettings()
+			client := clients.NewPasarGuardClient(baseURL, username, password)
+			if err := client.Login(); err != nil {
+				fmt.Printf("\n✗ Login failed: %v\n", err)
+				fmt.Println("\nPress Enter to return to the menu...")
+				reader.ReadString('\n')
+				continue
+			}
+			RunPasarGuardBulkEdit(client)
+			fmt.Println("\nPress Enter to return to the menu...")
+			reader.ReadString('\n')
+		case "5":
 			return
 		default:
 			fmt.Println("Invalid option. Please try again.")
@@ -250,7 +386,7 @@ func HandlePasarGuardMenu(reader *bufio.Reader) {
 }
 
 // RunExporter executes the main export logic for 3X-UI.
-func RunExporter(baseURL, username, password, filename string) {
+func RunExporter(baseURL, username, password, filename string, opts storage.Options) {
 	fmt.Println("\n" + utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
 	fmt.Println(utils.ColorBrightCyan + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightYellow+"📤 EXPORT PROCESS STARTED"+utils.ColorReset, 70) + utils.ColorBrightCyan + "║" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
@@ -287,7 +423,7 @@ func RunExporter(baseURL, username, password, filename string) {
 	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ Extracted data for %d users\n", totalUsers)
 	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " [4/4] " + utils.ColorBrightGreen + "Saving to JSON file..." + utils.ColorReset)
 	if len(inboundsData) > 0 {
-		if err := exporters.SaveToJSON(inboundsData, totalUsers, filename); err != nil {
+		if err := exporters.SaveToJSON(inboundsData, totalUsers, filename, opts); err != nil {
 			fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 			utils.PrintError(fmt.Sprintf("Error saving file: %v", err))
 		} else {
@@ -301,7 +437,7 @@ func RunExporter(baseURL, username, password, filename string) {
 }
 
 // RunUsersExporter exports 3X-UI users in PasarGuard-compatible format.
-func RunUsersExporter(baseURL, username, password, filename string) {
+func RunUsersExporter(baseURL, username, password, filename string, opts storage.Options) {
 	fmt.Println("\n" + utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
 	fmt.Println(utils.ColorBrightCyan + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightYellow+"📤 USERS EXPORT (PasarGuard Format)"+utils.ColorReset, 70) + utils.ColorBrightCyan + "║" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
@@ -336,9 +472,10 @@ func RunUsersExporter(baseURL, username, password, filename string) {
 		return
 	}
 	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ Extracted data for %d users\n", totalUsers)
+	merge := promptMergeOptions()
 	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " [4/4] " + utils.ColorBrightGreen + "Saving to JSON file..." + utils.ColorReset)
 	if len(inboundsData) > 0 {
-		if err := exporters.SaveThreeXUIUsersToJSON(inboundsData, filename); err != nil {
+		if err := exporters.SaveThreeXUIUsersToJSON(inboundsData, filename, opts, merge); err != nil {
 			fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 			utils.PrintError(fmt.Sprintf("Error saving file: %v", err))
 		} else {
@@ -355,8 +492,43 @@ func RunUsersExporter(baseURL, username, password, filename string) {
 	}
 }
 
+// promptMergeOptions asks whether clients of the same customer in several inbounds should be
+// exported as one multi-protocol user.
+func promptMergeOptions() exporters.MergeOptions {
+	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Merge clients of the same customer across inbounds:" + utils.ColorReset)
+	fmt.Println(" \t[1] Don't merge, one user per client (default)")
+	fmt.Println(" \t[2] Merge by email")
+	fmt.Println(" \t[3] Merge by subscription ID")
+	fmt.Println(" \t[4] Merge by a key taken from the email with a regular expression")
+	var merge exporters.MergeOptions
+	switch PromptForInputStyled("Select [1]", " ➜", utils.ColorBrightYellow) {
+	case "2":
+		merge.Key = exporters.MergeEmail
+	case "3":
+		merge.Key = exporters.MergeSubID
+	case "4":
+		for {
+			pattern, err := exporters.ParseMergePattern(PromptForInputStyled("Pattern (first capture group is the key, e.g. ^([^-_]+))", " ➜", utils.ColorBrightYellow))
+			if err == nil {
+				merge.Key, merge.Pattern = exporters.MergeCustom, pattern
+				break
+			}
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ %v\n"+utils.ColorReset, err)
+		}
+	default:
+		return merge
+	}
+	fmt.Println(" \t[1] Keep the largest quota, add up usage (default)")
+	fmt.Println(" \t[2] Add up quotas and usage")
+	merge.Quota = exporters.QuotaMax
+	if PromptForInputStyled("Select [1]", " ➜", utils.ColorBrightYellow) == "2" {
+		merge.Quota = exporters.QuotaSum
+	}
+	return merge
+}
+
 // RunPasarGuardExporter executes the export logic for PasarGuard panel (users only).
-func RunPasarGuardExporter(baseURL, username, password, filename string) {
+func RunPasarGuardExporter(baseURL, username, password, filename string, opts storage.Options) {
 	fmt.Println("\n" + utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
 	fmt.Println(utils.ColorBrightCyan + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightYellow+"📤 EXPORT PROCESS STARTED (PasarGuard)"+utils.ColorReset, 70) + utils.ColorBrightCyan + "║" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
@@ -384,7 +556,7 @@ func RunPasarGuardExporter(baseURL, username, password, filename string) {
 	}
 	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ Found %d user(s)\n", len(users))
 	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " [3/3] " + utils.ColorBrightGreen + "Saving to JSON file..." + utils.ColorReset)
-	if err := exporters.SavePasarGuardUsersToJSON(users, filename); err != nil {
+	if err := exporters.SavePasarGuardUsersToJSON(users, filename, opts); err != nil {
 		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 		utils.PrintError(fmt.Sprintf("Error saving file: %v", err))
 	} else {
@@ -0,0 +1,244 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"time"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/diff"
+	"panels_user_manager/pkg/importers"
+	"panels_user_manager/pkg/syncer"
+	"panels_user_manager/pkg/utils"
+)
+
+// ShowToolsMenu displays the cross-panel tools menu.
+func ShowToolsMenu() {
+	utils.ClearScreen()
+	fmt.Println("\n" + utils.ColorBrightYellow + utils.ColorBold + "🧰 TOOLS 🧰" + utils.ColorReset)
+	fmt.Println()
+	fmt.Println(utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Println(utils.ColorBold + utils.ColorBrightWhite + "                            📋 TOOLS MENU" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Println()
+
+	fmt.Println(utils.ColorBrightGreen + "  🔀 COMPARISON" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  ┌─────────────────────────────────────────────────────────────┐" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[1] Diff an export file against another file or a live panel" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + "     " + utils.ColorDim + "└─ Added, removed and modified users" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
+	fmt.Println()
+
+	fmt.Println(utils.ColorBrightYellow + "  🔄 SYNCHRONIZATION" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  ┌─────────────────────────────────────────────────────────────┐" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[2] Continuous sync 3X-UI → PasarGuard" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + "     " + utils.ColorDim + "└─ Push new clients, quota, expiry and status changes" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[3] Reconcile traffic between 3X-UI and PasarGuard" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + "     " + utils.ColorDim + "└─ Deduct usage on either panel from both quotas" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
+	fmt.Println()
+
+	fmt.Println(utils.ColorBrightRed + "  🔙 NAVIGATION" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  ┌─────────────────────────────────────────────────────────────┐" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  │" + utils.ColorReset + " " + utils.ColorBrightWhite + "[4] Return to main menu" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightBlue + "  └─────────────────────────────────────────────────────────────┘" + utils.ColorReset)
+	fmt.Println()
+
+	fmt.Print(utils.ColorBrightMagenta + "  ➜ Select an option (1-4): " + utils.ColorReset)
+}
+
+// HandleToolsMenu handles the tools menu operations.
+func HandleToolsMenu(reader *bufio.Reader) {
+	for {
+		ShowToolsMenu()
+		choice, _ := reader.ReadString('\n')
+		choice = strings.TrimSpace(choice)
+		switch choice {
+		case "1":
+			RunDiff()
+			fmt.Println("\nPress Enter to return to the menu...")
+			reader.ReadString('\n')
+		case "2":
+			RunSync()
+			fmt.Println("\nPress Enter to return to the menu...")
+			reader.ReadString('\n')
+		case "3":
+			RunReconcile()
+			fmt.Println("\nPress Enter to return to the menu...")
+			reader.ReadString('\n')
+		case "4":
+			return
+		default:
+			fmt.Println("Invalid option. Please try again.")
+			fmt.Println("\nPress Enter to continue...")
+			reader.ReadString('\n')
+		}
+	}
+}
+
+// RunDiff compares an export file with a second export file or with the current state of a live panel.
+func RunDiff() {
+	fmt.Println("\n" + utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightCyan+"🔀 SNAPSHOT DIFF"+utils.ColorReset, 70) + utils.ColorBrightMagenta + "║" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+
+	oldPath := PromptForInputStyled("Enter the path to the older export file", "\n ➜", utils.ColorBrightYellow)
+	older, err := loadSnapshot(oldPath)
+	if err != nil {
+		utils.PrintError(err.Error())
+		return
+	}
+
+	fmt.Println("\n" + utils.ColorBrightCyan + "Compare against:" + utils.ColorReset)
+	fmt.Println("  " + utils.ColorBrightWhite + "[1]" + utils.ColorReset + " Another export file")
+	fmt.Println("  " + utils.ColorBrightWhite + "[2]" + utils.ColorReset + " Live 3X-UI panel")
+	fmt.Println("  " + utils.ColorBrightWhite + "[3]" + utils.ColorReset + " Live PasarGuard panel")
+	var newer *diff.Snapshot
+	switch PromptForInputStyled("Select target [1]", " ➜", utils.ColorBrightYellow) {
+	case "2":
+		newer, err = liveThreeXUISnapshot()
+	case "3":
+		newer, err = livePasarGuardSnapshot()
+	default:
+		newPath := PromptForInputStyled("Enter the path to the newer export file", " ➜", utils.ColorBrightYellow)
+		newer, err = loadSnapshot(newPath)
+	}
+	if err != nil {
+		utils.PrintError(err.Error())
+		return
+	}
+
+	result := diff.Compare(older, newer)
+	diff.Print(result)
+
+	reportFile := PromptForInputStyled("Save diff as JSON to (leave empty to skip)", "\n ➜", utils.ColorBrightYellow)
+	if reportFile == "" {
+		return
+	}
+	if err := diff.Save(result, reportFile); err != nil {
+		utils.PrintError(err.Error())
+		return
+	}
+	utils.PrintSuccess(fmt.Sprintf("Diff saved to: %s", reportFile))
+}
+
+func loadSnapshot(filePath string) (*diff.Snapshot, error) {
+	opts, err := importers.DecryptionOptions(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file '%s': %v", filePath, err)
+	}
+	return diff.LoadSnapshot(filePath, opts)
+}
+
+func liveThreeXUISnapshot() (*diff.Snapshot, error) {
+	baseURL, username, password := GetLoginSettings()
+	client := clients.NewThreeXUIClient(baseURL, username, password)
+	if err := client.Login(); err != nil {
+		return nil, fmt.Errorf("failed to log in: %v", err)
+	}
+	inbounds, err := client.GetAllInbounds()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching inbounds: %v", err)
+	}
+	inboundsData, _, err := client.ExtractClientsFromInbounds(inbounds)
+	if err != nil {
+		return nil, fmt.Errorf("error extracting clients: %v", err)
+	}
+	return diff.FromInbounds(baseURL, inboundsData), nil
+}
+
+func livePasarGuardSnapshot() (*diff.Snapshot, error) {
+	baseURL, username, password := GetLoginSettings()
+	client := clients.NewPasarGuardClient(baseURL, username, password)
+	if err := client.Login(); err != nil {
+		return nil, fmt.Errorf("failed to log in: %v", err)
+	}
+	users, err := client.GetAllUsers()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching users: %v", err)
+	}
+	return diff.FromPasarGuardUsers(baseURL, users), nil
+}
+
+// RunSync periodically applies 3X-UI changes to PasarGuard until interrupted with Ctrl+C.
+func RunSync() {
+	fmt.Println("\n" + utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightCyan+"🔄 3X-UI → PASARGUARD SYNC"+utils.ColorReset, 70) + utils.ColorBrightMagenta + "║" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+
+	fmt.Println("\n" + utils.ColorBrightCyan + "Source: 3X-UI panel" + utils.ColorReset)
+	sourceURL, sourceUser, sourcePass := GetLoginSettings()
+	fmt.Println("\n" + utils.ColorBrightCyan + "Target: PasarGuard panel" + utils.ColorReset)
+	targetURL, targetUser, targetPass := GetLoginSettings()
+
+	cfg := syncer.Config{StateFile: syncer.DefaultStateFile}
+	if stateFile := PromptForInputStyled(fmt.Sprintf("State file [%s]", syncer.DefaultStateFile), "\n ➜", utils.ColorBrightYellow); stateFile != "" {
+		cfg.StateFile = stateFile
+	}
+	minutes := PromptForInputStyled("Sync interval in minutes, 0 to run once [10]", " ➜", utils.ColorBrightYellow)
+	if minutes == "" {
+		minutes = "10"
+	}
+	n, err := strconv.Atoi(minutes)
+	if err != nil || n < 0 {
+		utils.PrintError(fmt.Sprintf("Invalid interval '%s'", minutes))
+		return
+	}
+	cfg.Interval = time.Duration(n) * time.Minute
+	for _, part := range strings.Split(PromptForInputStyled("Group IDs for newly created users (comma-separated, Enter to skip)", " ➜", utils.ColorBrightYellow), ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil {
+			utils.PrintWarning(fmt.Sprintf("Invalid group ID '%s', skipping", part))
+			continue
+		}
+		cfg.GroupIDs = append(cfg.GroupIDs, id)
+	}
+
+	s, err := syncer.New(clients.NewThreeXUIClient(sourceURL, sourceUser, sourcePass), clients.NewPasarGuardClient(targetURL, targetUser, targetPass), cfg)
+	if err != nil {
+		utils.PrintError(err.Error())
+		return
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := s.Run(ctx); err != nil {
+		return
+	}
+	utils.PrintSuccess(fmt.Sprintf("Sync stopped. State saved to: %s", cfg.StateFile))
+}
+
+// RunReconcile deducts traffic used on either panel from the remaining quota on both.
+func RunReconcile() {
+	fmt.Println("\n" + utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightCyan+"⚖️ TRAFFIC RECONCILIATION"+utils.ColorReset, 70) + utils.ColorBrightMagenta + "║" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
+
+	fmt.Println("\n" + utils.ColorBrightCyan + "3X-UI panel" + utils.ColorReset)
+	sourceURL, sourceUser, sourcePass := GetLoginSettings()
+	fmt.Println("\n" + utils.ColorBrightCyan + "PasarGuard panel" + utils.ColorReset)
+	targetURL, targetUser, targetPass := GetLoginSettings()
+
+	stateFile := PromptForInputStyled(fmt.Sprintf("State file [%s]", syncer.DefaultReconcileStateFile), "\n ➜", utils.ColorBrightYellow)
+	dryRun := strings.ToLower(PromptForInputStyled("Dry run, only show what would change? (y/N)", " ➜", utils.ColorBrightYellow)) == "y"
+
+	r, err := syncer.NewReconciler(clients.NewThreeXUIClient(sourceURL, sourceUser, sourcePass), clients.NewPasarGuardClient(targetURL, targetUser, targetPass), stateFile)
+	if err != nil {
+		utils.PrintError(err.Error())
+		return
+	}
+	report, err := r.Run(dryRun)
+	if err != nil {
+		utils.PrintError(fmt.Sprintf("Reconciliation failed: %v", err))
+		return
+	}
+	syncer.PrintReconcileReport(report, dryRun)
+}
@@ -0,0 +1,300 @@
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/storage"
+	"panels_user_manager/pkg/utils"
+)
+
+// Entry is a panel-independent view of one user, used to compare snapshots.
+type Entry struct {
+	Key      string `json:"key"`
+	Username string `json:"username"`
+	UUID     string `json:"uuid"`
+	Enable   bool   `json:"enable"`
+	Quota    int64  `json:"quota"`  // bytes, 0 = unlimited
+	Used     int64  `json:"used"`   // bytes
+	Expiry   int64  `json:"expiry"` // unix seconds, 0 = never, negative = delayed start duration
+	Inbound  string `json:"inbound,omitempty"`
+}
+
+// Snapshot is a set of users taken from an export file or a live panel.
+type Snapshot struct {
+	Source  string
+	Entries map[string]Entry
+	order   []string
+}
+
+// Change is a single field that differs between two snapshots.
+type Change struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// UserDiff lists the changed fields of a user present in both snapshots.
+type UserDiff struct {
+	Key      string   `json:"key"`
+	Username string   `json:"username"`
+	Changes  []Change `json:"changes"`
+}
+
+// Result is the outcome of comparing two snapshots.
+type Result struct {
+	GeneratedAt string     `json:"generated_at"`
+	OldSource   string     `json:"old_source"`
+	NewSource   string     `json:"new_source"`
+	Added       []Entry    `json:"added"`
+	Removed     []Entry    `json:"removed"`
+	Modified    []UserDiff `json:"modified"`
+	Unchanged   int        `json:"unchanged"`
+}
+
+func newSnapshot(source string) *Snapshot {
+	return &Snapshot{Source: source, Entries: make(map[string]Entry)}
+}
+
+// add stores an entry, suffixing duplicate keys with _1, _2... the same way the importer renames them.
+func (s *Snapshot) add(e Entry) {
+	base := normalizeKey(e.Username)
+	if base == "" {
+		base = strings.ToLower(strings.TrimSpace(e.UUID))
+	}
+	key := base
+	for i := 1; ; i++ {
+		if _, exists := s.Entries[key]; !exists {
+			break
+		}
+		key = fmt.Sprintf("%s_%d", base, i)
+	}
+	e.Key = key
+	s.Entries[key] = e
+	s.order = append(s.order, key)
+}
+
+// FromInbounds builds a snapshot from 3X-UI inbound data (export file or live panel).
+func FromInbounds(source string, inbounds []models.InboundData) *Snapshot {
+	s := newSnapshot(source)
+	for _, inbound := range inbounds {
+		s.addInbound(inbound)
+	}
+	return s
+}
+
+func (s *Snapshot) addInbound(inbound models.InboundData) {
+	for _, client := range inbound.Clients {
+		// Trojan and shadowsocks clients have a password instead of an ID.
+		uuid := client.ClientID
+		if uuid == "" {
+			uuid = client.ClientPassword
+		}
+		s.add(Entry{
+			Username: client.ClientEmail,
+			UUID:     uuid,
+			Enable:   client.ClientEnable,
+			Quota:    client.ClientTotalGB,
+			Used:     client.TrafficUsed,
+			Expiry:   normalizeExpiry(client.ClientExpiryTime),
+			Inbound:  fmt.Sprintf("%s:%d", inbound.Remark, inbound.Port),
+		})
+	}
+}
+
+// FromPasarGuardUsers builds a snapshot from PasarGuard-format users (export file or live panel).
+func FromPasarGuardUsers(source string, users []models.PasarGuardUser) *Snapshot {
+	s := newSnapshot(source)
+	for _, user := range users {
+		s.addUser(user)
+	}
+	return s
+}
+
+func (s *Snapshot) addUser(user models.PasarGuardUser) {
+	username := user.Username
+	if username == "" {
+		username = user.Email
+	}
+	expiry := user.ExpiryTime
+	if expiry == 0 && user.OnHoldExpireDuration > 0 {
+		expiry = -user.OnHoldExpireDuration * 1000
+	}
+	s.add(Entry{
+		Username: username,
+		UUID:     user.UUID,
+		Enable:   user.Enable,
+		Quota:    user.TotalGB,
+		Used:     user.UsedTraffic,
+		Expiry:   normalizeExpiry(expiry),
+	})
+}
+
+// LoadSnapshot reads a 3X-UI (OutputFile) or PasarGuard (PasarGuardUsersExportFile) export,
+// in any of the supported formats, and detects which of the two it is.
+func LoadSnapshot(filename string, opts storage.Options) (*Snapshot, error) {
+	s := newSnapshot(filename)
+	inbounds, err := storage.OpenRecords[models.InboundData](filename, opts, storage.KindInbounds)
+	if err == nil {
+		defer inbounds.Close()
+		return s, readAll(inbounds, s.addInbound)
+	}
+	users, usersErr := storage.OpenRecords[models.PasarGuardUser](filename, opts, storage.KindUsers)
+	if usersErr != nil {
+		return nil, fmt.Errorf("'%s' is not a 3X-UI or PasarGuard export: %v", filename, usersErr)
+	}
+	defer users.Close()
+	return s, readAll(users, s.addUser)
+}
+
+// readAll decodes every record and hands it to fn.
+func readAll[T any](records *storage.RecordReader[T], fn func(T)) error {
+	for {
+		record, err := records.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(record)
+	}
+}
+
+// Compare lists users added to, removed from and modified in newer compared to older.
+func Compare(older, newer *Snapshot) *Result {
+	result := &Result{
+		GeneratedAt: time.Now().Format(time.RFC3339),
+		OldSource:   older.Source,
+		NewSource:   newer.Source,
+		Added:       []Entry{},
+		Removed:     []Entry{},
+		Modified:    []UserDiff{},
+	}
+	for _, key := range older.order {
+		oldEntry := older.Entries[key]
+		newEntry, ok := newer.Entries[key]
+		if !ok {
+			result.Removed = append(result.Removed, oldEntry)
+			continue
+		}
+		changes := compareEntries(oldEntry, newEntry)
+		if len(changes) == 0 {
+			result.Unchanged++
+			continue
+		}
+		result.Modified = append(result.Modified, UserDiff{Key: key, Username: newEntry.Username, Changes: changes})
+	}
+	for _, key := range newer.order {
+		if _, ok := older.Entries[key]; !ok {
+			result.Added = append(result.Added, newer.Entries[key])
+		}
+	}
+	sort.Slice(result.Modified, func(i, j int) bool { return result.Modified[i].Key < result.Modified[j].Key })
+	return result
+}
+
+func compareEntries(a, b Entry) []Change {
+	var changes []Change
+	if !strings.EqualFold(strings.TrimSpace(a.UUID), strings.TrimSpace(b.UUID)) {
+		changes = append(changes, Change{"uuid", a.UUID, b.UUID})
+	}
+	if normalizeQuota(a.Quota) != normalizeQuota(b.Quota) {
+		changes = append(changes, Change{"quota", formatQuota(a.Quota), formatQuota(b.Quota)})
+	}
+	if a.Expiry != b.Expiry {
+		changes = append(changes, Change{"expiry", formatExpiry(a.Expiry), formatExpiry(b.Expiry)})
+	}
+	if a.Enable != b.Enable {
+		changes = append(changes, Change{"enable", fmt.Sprint(a.Enable), fmt.Sprint(b.Enable)})
+	}
+	if a.Inbound != "" && b.Inbound != "" && a.Inbound != b.Inbound {
+		changes = append(changes, Change{"inbound", a.Inbound, b.Inbound})
+	}
+	return changes
+}
+
+// Print shows the result in the terminal.
+func Print(result *Result) {
+	fmt.Println("\n" + utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Println(utils.ColorBrightCyan + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightYellow+"🔀 SNAPSHOT DIFF"+utils.ColorReset, 70) + utils.ColorBrightCyan + "║" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Printf("\n "+utils.ColorCyan+"Old: "+utils.ColorReset+"%s\n", result.OldSource)
+	fmt.Printf(" "+utils.ColorCyan+"New: "+utils.ColorReset+"%s\n", result.NewSource)
+
+	fmt.Println("\n " + utils.ColorBrightGreen + "┌─ ADDED USERS" + utils.ColorReset)
+	for _, e := range result.Added {
+		fmt.Printf(" │ "+utils.ColorGreen+"+ %s"+utils.ColorReset+" (quota %s, expiry %s)\n", e.Username, formatQuota(e.Quota), formatExpiry(e.Expiry))
+	}
+	fmt.Printf(" "+utils.ColorBrightGreen+"└ %d added"+utils.ColorReset+"\n", len(result.Added))
+
+	fmt.Println("\n " + utils.ColorBrightRed + "┌─ REMOVED USERS" + utils.ColorReset)
+	for _, e := range result.Removed {
+		fmt.Printf(" │ "+utils.ColorRed+"- %s"+utils.ColorReset+" (%s)\n", e.Username, e.UUID)
+	}
+	fmt.Printf(" "+utils.ColorBrightRed+"└ %d removed"+utils.ColorReset+"\n", len(result.Removed))
+
+	fmt.Println("\n " + utils.ColorBrightYellow + "┌─ MODIFIED USERS" + utils.ColorReset)
+	for _, m := range result.Modified {
+		fmt.Printf(" │ "+utils.ColorYellow+"~ %s"+utils.ColorReset+"\n", m.Username)
+		for _, c := range m.Changes {
+			fmt.Printf(" │     %-8s %s → %s\n", c.Field, c.Old, c.New)
+		}
+	}
+	fmt.Printf(" "+utils.ColorBrightYellow+"└ %d modified, %d unchanged"+utils.ColorReset+"\n", len(result.Modified), result.Unchanged)
+}
+
+// Save writes the result as JSON. It contains UUIDs, so it is saved with mode 0600.
+func Save(result *Result, filename string) error {
+	data, err := json.MarshalIndent(result, "", " ")
+	if err != nil {
+		return fmt.Errorf("error creating diff JSON: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("error saving diff: %v", err)
+	}
+	return nil
+}
+
+// normalizeKey matches the importer's username sanitizing so 3X-UI emails line up with PasarGuard usernames.
+func normalizeKey(name string) string {
+	return strings.TrimSpace(strings.ReplaceAll(strings.ToLower(name), " ", "_"))
+}
+
+// normalizeExpiry converts 3X-UI millisecond values (including negative delayed-start durations) to seconds.
+func normalizeExpiry(expiry int64) int64 {
+	if expiry > 1e11 || expiry < -1e6 {
+		return expiry / 1000
+	}
+	return expiry
+}
+
+func normalizeQuota(quota int64) int64 {
+	if quota < 0 {
+		return 0
+	}
+	return quota
+}
+
+func formatQuota(quota int64) string {
+	if normalizeQuota(quota) == 0 {
+		return "unlimited"
+	}
+	return utils.FormatBytes(quota)
+}
+
+func formatExpiry(expiry int64) string {
+	switch {
+	case expiry == 0:
+		return "never"
+	case expiry < 0:
+		return fmt.Sprintf("%d days after first use", -expiry/86400)
+	}
+	return time.Unix(expiry, 0).Format("2006-01-02 15:04")
+}
@@ -3,17 +3,17 @@ package exporters
 import (
 	"encoding/json"
 	"fmt"
-	"os"
 	"sort"
 	"strings"
 	"time"
 
 	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/storage"
 	"panels_user_manager/pkg/utils"
 )
 
 // SaveToJSON saves the extracted 3X-UI data (inbounds + users) to a JSON file and prints stats.
-func SaveToJSON(inboundsData []models.InboundData, totalUsers int, filename string) error {
+func SaveToJSON(inboundsData []models.InboundData, totalUsers int, filename string, opts storage.Options) error {
 	output := models.OutputFile{
 		ExportDate:    time.Now().Format(time.RFC3339),
 		TotalInbounds: len(inboundsData),
@@ -21,53 +21,36 @@ func SaveToJSON(inboundsData []models.InboundData, totalUsers int, filename stri
 		Inbounds:      inboundsData,
 	}
 
-	fileData, err := json.MarshalIndent(output, "", " ")
-	if err != nil {
-		return fmt.Errorf("error creating output JSON: %v", err)
+	header := storage.Header{Kind: storage.KindInbounds, ExportDate: output.ExportDate, PanelType: "3X-UI", TotalInbounds: output.TotalInbounds, TotalUsers: output.TotalUsers}
+	if err := writeExport(filename, opts, header, output, inboundsData); err != nil {
+		return err
 	}
-	if err := os.WriteFile(filename, fileData, 0644); err != nil {
-		return fmt.Errorf("error saving file: %v", err)
+
+	// Calculate stats from inbounds data and record them in the integrity manifest
+	manifest := storage.Manifest{Kind: storage.KindInbounds}
+	for _, inbound := range inboundsData {
+		manifest.AddInbound(inbound)
 	}
+	if err := storage.WriteManifest(filename, manifest); err != nil {
+		return err
+	}
+	activeUsers := manifest.ActiveUsers
+	totalTrafficUsed, totalTrafficLimit, totalTrafficRemaining := manifest.TrafficUsed, manifest.TrafficAllocated, manifest.TrafficRemaining
 
 	// --- Display stats ---
 	fmt.Println("\n" + utils.ColorBrightGreen + strings.Repeat("═", 72) + utils.ColorReset)
 	fmt.Println(utils.ColorBrightGreen + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightCyan+"✅ EXPORT COMPLETED SUCCESSFULLY"+utils.ColorReset, 70) + utils.ColorBrightGreen + "║" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightGreen + strings.Repeat("═", 72) + utils.ColorReset)
 
-	activeUsers := 0
-	var totalTrafficUsed, totalTrafficLimit, totalTrafficRemaining int64
-
-	// Calculate stats from inbounds data
-	for _, inbound := range inboundsData {
-		for _, client := range inbound.Clients {
-			if client.ClientEnable {
-				activeUsers++
-			}
-			totalTrafficUsed += client.TrafficUsed
-			if client.ClientTotalGB > 0 {
-				totalTrafficLimit += client.ClientTotalGB
-			}
-			if client.TrafficRemaining > 0 {
-				totalTrafficRemaining += client.TrafficRemaining
-			}
-		}
-	}
-
 	fmt.Println("\n " + utils.ColorBrightBlue + "┌─ GENERAL INFORMATION" + utils.ColorReset)
 	fmt.Printf(" │ "+utils.ColorGreen+"📁 Export File: "+utils.ColorReset+"%s\n", filename)
 	fmt.Printf(" │ "+utils.ColorCyan+"🕐 Export Date: "+utils.ColorReset+"%s\n", time.Now().Format("2006-01-02 15:04:05"))
+	fmt.Printf(" │ "+utils.ColorMagenta+"🔏 Manifest: "+utils.ColorReset+"%s\n", filename+storage.ManifestSuffix)
 	fmt.Println(" " + utils.ColorBrightBlue + "└" + utils.ColorReset)
 	fmt.Println("\n " + utils.ColorBrightGreen + "┌─ INBOUND STATISTICS" + utils.ColorReset)
 	fmt.Printf(" │ "+utils.ColorYellow+"📍 Total Inbounds: "+utils.ColorReset+"%d\n", len(inboundsData))
-	enabledInbounds := 0
-	disabledInbounds := 0
-	for _, ib := range inboundsData {
-		if ib.Enable {
-			enabledInbounds++
-		} else {
-			disabledInbounds++
-		}
-	}
+	enabledInbounds := manifest.EnabledInbounds
+	disabledInbounds := manifest.TotalInbounds - manifest.EnabledInbounds
 	fmt.Printf(" │ "+utils.ColorGreen+"✅ Enabled: "+utils.ColorReset+"%d | "+utils.ColorRed+"❌ Disabled: "+utils.ColorReset+"%d\n", enabledInbounds, disabledInbounds)
 	fmt.Println(" " + utils.ColorBrightGreen + "└" + utils.ColorReset)
 	fmt.Println("\n " + utils.ColorBrightMagenta + "┌─ USER STATISTICS" + utils.ColorReset)
@@ -91,17 +74,22 @@ func SaveToJSON(inboundsData []models.InboundData, totalUsers int, filename stri
 
 // SaveThreeXUIUsersToJSON saves 3X-UI users in PasarGuard format for compatibility
 // تمام کاربران را به ساختار PasarGuard convert می‌کند تا با فایل‌های PasarGuard compatible باشند
-func SaveThreeXUIUsersToJSON(inboundsData []models.InboundData, filename string) error {
+// With merge.Key set, clients of several inbounds that share the key become one user whose
+// proxy_settings carry every protocol's credentials.
+func SaveThreeXUIUsersToJSON(inboundsData []models.InboundData, filename string, opts storage.Options, merge MergeOptions) error {
 	var users []models.PasarGuardUser
-	userID := 1
+	var keys []string
 
 	for _, inbound := range inboundsData {
 		for _, client := range inbound.Clients {
+			uuid := client.ClientID
+			if uuid == "" {
+				uuid = client.ClientPassword
+			}
 			user := models.PasarGuardUser{
-				ID:               userID,
 				Username:         client.ClientEmail,
 				Email:            client.ClientEmail,
-				UUID:             client.ClientID,
+				UUID:             uuid,
 				Enable:           client.ClientEnable,
 				TotalGB:          client.ClientTotalGB,
 				ExpiryTime:       client.ClientExpiryTime,
@@ -111,15 +99,33 @@ func SaveThreeXUIUsersToJSON(inboundsData []models.InboundData, filename string)
 				Protocol:         inbound.Protocol,
 				Port:             inbound.Port,
 				Remark:           inbound.Remark,
+				InboundTag:       inbound.Tag,
 				SubscriptionURL:  "",
 				Note:             inbound.Remark,
 				ProxySettings:    make(map[string]interface{}),
 				GroupIDs:         []int{},
+
+				DataLimitResetStrategy: models.ResetStrategyFromDays(client.ClientReset),
+				ResetDays:              client.ClientReset,
+			}
+			if client.ClientExpiryTime < 0 {
+				user.OnHoldExpireDuration = -client.ClientExpiryTime / 1000
+			}
+			if protocol, settings := clientProxySettings(inbound, client); settings != nil {
+				user.ProxySettings[protocol] = settings
 			}
 			users = append(users, user)
-			userID++
+			keys = append(keys, merge.mergeKey(client))
 		}
 	}
+	if merge.Key != MergeNone {
+		before := len(users)
+		users = mergeUsers(users, keys, merge.Quota, merge.Key == MergeCustom)
+		fmt.Printf(" "+utils.ColorGreen+"✓ Merged %d client(s) into %d user(s)\n"+utils.ColorReset, before, len(users))
+	}
+	for i := range users {
+		users[i].ID = i + 1
+	}
 
 	output := models.PasarGuardUsersExportFile{
 		ExportDate: time.Now().Format(time.RFC3339),
@@ -128,56 +134,61 @@ func SaveThreeXUIUsersToJSON(inboundsData []models.InboundData, filename string)
 		Users:      users,
 	}
 
-	fileData, err := json.MarshalIndent(output, "", " ")
-	if err != nil {
-		return fmt.Errorf("error creating output JSON: %v", err)
-	}
-	if err := os.WriteFile(filename, fileData, 0644); err != nil {
-		return fmt.Errorf("error saving file: %v", err)
+	header := storage.Header{Kind: storage.KindUsers, ExportDate: output.ExportDate, PanelType: output.PanelType, TotalUsers: output.TotalUsers}
+	if err := writeExport(filename, opts, header, output, users); err != nil {
+		return err
 	}
 
-	return nil
+	manifest := storage.Manifest{Kind: storage.KindUsers}
+	for _, user := range users {
+		manifest.AddUser(user)
+	}
+	return storage.WriteManifest(filename, manifest)
 }
 
 // SavePasarGuardUsersToJSON saves PasarGuard users to a JSON file and prints stats.
-func SavePasarGuardUsersToJSON(users []models.PasarGuardUser, filename string) error {
+func SavePasarGuardUsersToJSON(users []models.PasarGuardUser, filename string, opts storage.Options) error {
 	output := models.PasarGuardUsersExportFile{
 		ExportDate: time.Now().Format(time.RFC3339),
 		PanelType:  "PasarGuard",
 		TotalUsers: len(users),
-		Users:      users,
 	}
-	fileData, err := json.MarshalIndent(output, "", " ")
-	if err != nil {
-		return fmt.Errorf("error creating output JSON: %v", err)
+	header := storage.Header{Kind: storage.KindUsers, ExportDate: output.ExportDate, PanelType: output.PanelType, TotalUsers: output.TotalUsers}
+	if opts.Streaming {
+		if err := writeRecords(filename, opts, header, users, asThreeXUIExpiry); err != nil {
+			return err
+		}
+	} else {
+		// The document is encoded in one piece, so it gets converted copies of the caller's users.
+		output.Users = make([]models.PasarGuardUser, len(users))
+		for i, user := range users {
+			output.Users[i] = asThreeXUIExpiry(user)
+		}
+		if err := writeExport(filename, opts, header, output, output.Users); err != nil {
+			return err
+		}
 	}
-	if err := os.WriteFile(filename, fileData, 0644); err != nil {
-		return fmt.Errorf("error saving file: %v", err)
+
+	manifest := storage.Manifest{Kind: storage.KindUsers}
+	for _, user := range users {
+		manifest.AddUser(user)
+	}
+	if err := storage.WriteManifest(filename, manifest); err != nil {
+		return err
 	}
 
 	fmt.Println("\n" + utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
 	fmt.Println(utils.ColorBrightCyan + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightYellow+"📊 EXPORT STATISTICS (PasarGuard)"+utils.ColorReset, 70) + utils.ColorBrightCyan + "║" + utils.ColorReset)
 	fmt.Println(utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
 
-	activeUsers := 0
-	var totalTrafficUsed, totalTrafficLimit, totalTrafficRemaining int64
-	for _, user := range users {
-		if user.Enable {
-			activeUsers++
-		}
-		totalTrafficUsed += user.UsedTraffic
-		if user.TotalGB > 0 {
-			totalTrafficLimit += user.TotalGB
-		}
-		if user.RemainingTraffic > 0 {
-			totalTrafficRemaining += user.RemainingTraffic
-		}
-	}
+	activeUsers := manifest.ActiveUsers
+	totalTrafficUsed, totalTrafficLimit, totalTrafficRemaining := manifest.TrafficUsed, manifest.TrafficAllocated, manifest.TrafficRemaining
 
 	fmt.Println("\n " + utils.ColorBrightBlue + "┌─ GENERAL INFORMATION" + utils.ColorReset)
 	fmt.Printf(" │ "+utils.ColorGreen+"📁 Export File: "+utils.ColorReset+"%s\n", filename)
 	fmt.Printf(" │ "+utils.ColorCyan+"🕐 Export Date: "+utils.ColorReset+"%s\n", time.Now().Format("2006-01-02 15:04:05"))
 	fmt.Printf(" │ " + utils.ColorMagenta + "🌐 Panel Type: " + utils.ColorReset + "PasarGuard\n")
+	fmt.Printf(" │ "+utils.ColorMagenta+"🔏 Manifest: "+utils.ColorReset+"%s\n", filename+storage.ManifestSuffix)
 	fmt.Println(" " + utils.ColorBrightBlue + "└" + utils.ColorReset)
 	fmt.Println("\n " + utils.ColorBrightMagenta + "┌─ USER STATISTICS" + utils.ColorReset)
 	fmt.Printf(" │ "+utils.ColorMagenta+"👥 Total Users: "+utils.ColorReset+"%d\n", len(users))
@@ -230,3 +241,57 @@ func SavePasarGuardUsersToJSON(users []models.PasarGuardUser, filename string) e
 	fmt.Println(utils.ColorBrightGreen + strings.Repeat("═", 72) + utils.ColorReset + "\n")
 	return nil
 }
+
+// asThreeXUIExpiry returns a copy of user as it is written to a PasarGuard export. On-hold users
+// get a negative expiry (validity in milliseconds, counted from first use), the 3X-UI
+// delayed-start convention, so the file reads the same as one exported from 3X-UI. The reset
+// strategy is likewise written as a 3X-UI reset period.
+func asThreeXUIExpiry(user models.PasarGuardUser) models.PasarGuardUser {
+	user.ResetDays = models.ResetDaysFromStrategy(user.DataLimitResetStrategy)
+	if user.ExpiryTime == 0 && user.OnHoldExpireDuration > 0 {
+		user.ExpiryTime = -user.OnHoldExpireDuration * 1000
+	}
+	return user
+}
+
+// writeExport writes an export either as an NDJSON stream (header line plus one record per line)
+// or as a single indented JSON document, encoding directly into the file instead of buffering it.
+func writeExport[T any](filename string, opts storage.Options, header storage.Header, document interface{}, records []T) error {
+	if opts.Streaming {
+		return writeRecords(filename, opts, header, records, func(record T) T { return record })
+	}
+
+	w, err := storage.Create(filename, opts)
+	if err != nil {
+		return fmt.Errorf("error saving file: %v", err)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	if err := enc.Encode(document); err != nil {
+		w.Close()
+		return fmt.Errorf("error creating output JSON: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error saving file: %v", err)
+	}
+	return nil
+}
+
+// writeRecords writes an NDJSON stream, passing each record through convert as it is written so
+// that records itself is left untouched.
+func writeRecords[T any](filename string, opts storage.Options, header storage.Header, records []T, convert func(T) T) error {
+	rw, err := storage.NewRecordWriter(filename, opts, header)
+	if err != nil {
+		return fmt.Errorf("error saving file: %v", err)
+	}
+	for _, record := range records {
+		if err := rw.Write(convert(record)); err != nil {
+			rw.Close()
+			return fmt.Errorf("error writing record: %v", err)
+		}
+	}
+	if err := rw.Close(); err != nil {
+		return fmt.Errorf("error saving file: %v", err)
+	}
+	return nil
+}
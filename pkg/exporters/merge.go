@@ -0,0 +1,170 @@
+package exporters
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// Keys that identify clients belonging to the same customer.
+const (
+	MergeNone   = ""
+	MergeEmail  = "email"
+	MergeSubID  = "subid"
+	MergeCustom = "custom" // first capture group (or whole match) of MergeOptions.Pattern applied to the email
+)
+
+// How quotas and usage of merged clients are combined.
+const (
+	QuotaMax = "max" // largest quota; usage is summed since every client consumed the same plan
+	QuotaSum = "sum" // quotas and usage are both summed
+)
+
+// MergeOptions controls how clients of several inbounds are folded into one PasarGuard user.
+type MergeOptions struct {
+	Key     string
+	Pattern *regexp.Regexp
+	Quota   string
+}
+
+// mergeKey returns the key a client is grouped under, or "" when it is exported on its own.
+func (o MergeOptions) mergeKey(client models.ClientDetails) string {
+	switch o.Key {
+	case MergeEmail:
+		return strings.ToLower(strings.TrimSpace(client.ClientEmail))
+	case MergeSubID:
+		return strings.TrimSpace(client.ClientSubID)
+	case MergeCustom:
+		if o.Pattern == nil {
+			return ""
+		}
+		m := o.Pattern.FindStringSubmatch(client.ClientEmail)
+		if len(m) > 1 {
+			return m[1]
+		}
+		if len(m) == 1 {
+			return m[0]
+		}
+	}
+	return ""
+}
+
+// clientProxySettings returns the PasarGuard proxy_settings entry for a 3X-UI client.
+func clientProxySettings(inbound models.InboundData, client models.ClientDetails) (string, map[string]interface{}) {
+	password := client.ClientPassword
+	if password == "" {
+		password = client.ClientID
+	}
+	switch inbound.Protocol {
+	case "vmess":
+		return "vmess", map[string]interface{}{"id": client.ClientID}
+	case "vless":
+		return "vless", map[string]interface{}{"id": client.ClientID, "flow": client.ClientFlow}
+	case "trojan":
+		return "trojan", map[string]interface{}{"password": password}
+	case "shadowsocks":
+		var settings struct {
+			Method string `json:"method"`
+		}
+		json.Unmarshal([]byte(inbound.OriginalSettings), &settings)
+		if client.ClientMethod != "" {
+			settings.Method = client.ClientMethod
+		}
+		return "shadowsocks", map[string]interface{}{"password": password, "method": settings.Method}
+	}
+	return "", nil
+}
+
+// mergeUsers folds users that share a merge key into the first of them. keys[i] is the merge key
+// of users[i]; users with an empty key are kept as they are. With renameToKey, merged users are
+// named after their key instead of the first client's email.
+func mergeUsers(users []models.PasarGuardUser, keys []string, quota string, renameToKey bool) []models.PasarGuardUser {
+	var merged []models.PasarGuardUser
+	index := make(map[string]int)
+	for i, user := range users {
+		pos, exists := index[keys[i]]
+		if keys[i] == "" || !exists {
+			if keys[i] != "" {
+				index[keys[i]] = len(merged)
+			}
+			merged = append(merged, user)
+			continue
+		}
+
+		into := &merged[pos]
+		if renameToKey {
+			into.Username = keys[i]
+		}
+		utils.VerboseLog("Merging %s (%s) into %s", user.Email, user.Protocol, into.Username)
+		for protocol, settings := range user.ProxySettings {
+			if _, taken := into.ProxySettings[protocol]; taken {
+				utils.VerboseLog("User %s already has %s credentials, keeping the first", into.Username, protocol)
+				continue
+			}
+			into.ProxySettings[protocol] = settings
+		}
+		if into.TotalGB > 0 && user.TotalGB > 0 {
+			if quota == QuotaSum {
+				into.TotalGB += user.TotalGB
+			} else if user.TotalGB > into.TotalGB {
+				into.TotalGB = user.TotalGB
+			}
+		} else {
+			into.TotalGB = 0 // unlimited wins
+		}
+		into.UsedTraffic += user.UsedTraffic
+		into.RemainingTraffic = 0
+		if into.TotalGB > 0 && into.TotalGB > into.UsedTraffic {
+			into.RemainingTraffic = into.TotalGB - into.UsedTraffic
+		}
+		into.ExpiryTime = laterExpiry(into.ExpiryTime, user.ExpiryTime)
+		into.OnHoldExpireDuration = 0
+		if into.ExpiryTime < 0 {
+			into.OnHoldExpireDuration = -into.ExpiryTime / 1000
+		}
+		into.Enable = into.Enable || user.Enable
+		if user.LimitIP > into.LimitIP {
+			into.LimitIP = user.LimitIP
+		}
+		if into.ResetDays == 0 && user.ResetDays > 0 {
+			into.ResetDays, into.DataLimitResetStrategy = user.ResetDays, user.DataLimitResetStrategy
+		}
+		into.Note += ", " + user.Remark
+	}
+	return merged
+}
+
+// laterExpiry returns the expiry that grants more time. 0 means never and always wins. A negative
+// value is a delayed start in milliseconds: a fixed date wins over it, since the customer is already
+// using the plan on another inbound, and of two delayed starts the longer one wins.
+func laterExpiry(a, b int64) int64 {
+	switch {
+	case a == 0 || b == 0:
+		return 0
+	case a < 0 && b < 0:
+		if b < a {
+			return b
+		}
+		return a
+	case a < 0:
+		return b
+	case b < 0:
+		return a
+	case b > a:
+		return b
+	}
+	return a
+}
+
+// ParseMergePattern compiles a custom merge pattern, e.g. `^([^-_]+)` to merge "john-vless" and "john_trojan".
+func ParseMergePattern(pattern string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid merge pattern: %v", err)
+	}
+	return re, nil
+}
@@ -0,0 +1,100 @@
+package exporters
+
+import (
+	"testing"
+
+	"panels_user_manager/pkg/models"
+)
+
+func TestMergeUsers(t *testing.T) {
+	const gb = 1 << 30
+	vless := models.PasarGuardUser{
+		Username: "john-vless", Enable: false, TotalGB: 10 * gb, UsedTraffic: 4 * gb, ExpiryTime: 1000, LimitIP: 1, Remark: "vless",
+		ProxySettings: map[string]interface{}{"vless": map[string]interface{}{"id": "a"}},
+	}
+	trojan := models.PasarGuardUser{
+		Username: "john-trojan", Enable: true, TotalGB: 20 * gb, UsedTraffic: 3 * gb, ExpiryTime: 2000, LimitIP: 2, Remark: "trojan",
+		ProxySettings: map[string]interface{}{"trojan": map[string]interface{}{"password": "p"}, "vless": map[string]interface{}{"id": "b"}},
+		ResetDays:     30, DataLimitResetStrategy: models.ResetMonth,
+	}
+	unlimited := trojan
+	unlimited.TotalGB = 0
+
+	tests := []struct {
+		name          string
+		users         []models.PasarGuardUser
+		keys          []string
+		quota         string
+		rename        bool
+		wantUsers     int
+		wantName      string
+		wantTotal     int64
+		wantUsed      int64
+		wantRemaining int64
+	}{
+		{"max quota, summed usage", []models.PasarGuardUser{vless, trojan}, []string{"john", "john"}, QuotaMax, false, 1, "john-vless", 20 * gb, 7 * gb, 13 * gb},
+		{"summed quota", []models.PasarGuardUser{vless, trojan}, []string{"john", "john"}, QuotaSum, false, 1, "john-vless", 30 * gb, 7 * gb, 23 * gb},
+		{"unlimited wins", []models.PasarGuardUser{vless, unlimited}, []string{"john", "john"}, QuotaMax, false, 1, "john-vless", 0, 7 * gb, 0},
+		{"renamed to key", []models.PasarGuardUser{vless, trojan}, []string{"john", "john"}, QuotaMax, true, 1, "john", 20 * gb, 7 * gb, 13 * gb},
+		{"empty keys are not merged", []models.PasarGuardUser{vless, trojan}, []string{"", ""}, QuotaMax, false, 2, "john-vless", 10 * gb, 4 * gb, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := make([]models.PasarGuardUser, len(tt.users))
+			for i, u := range tt.users {
+				// mergeUsers writes into the first user's proxy settings.
+				u.ProxySettings = copySettings(u.ProxySettings)
+				users[i] = u
+			}
+			merged := mergeUsers(users, tt.keys, tt.quota, tt.rename)
+			if len(merged) != tt.wantUsers {
+				t.Fatalf("got %d user(s), want %d", len(merged), tt.wantUsers)
+			}
+			got := merged[0]
+			if got.Username != tt.wantName || got.TotalGB != tt.wantTotal || got.UsedTraffic != tt.wantUsed || got.RemainingTraffic != tt.wantRemaining {
+				t.Errorf("got %s total %d used %d remaining %d, want %s total %d used %d remaining %d",
+					got.Username, got.TotalGB, got.UsedTraffic, got.RemainingTraffic, tt.wantName, tt.wantTotal, tt.wantUsed, tt.wantRemaining)
+			}
+			if tt.wantUsers > 1 {
+				return
+			}
+			if !got.Enable || got.LimitIP != 2 || got.ExpiryTime != 2000 || got.ResetDays != 30 || got.Note != ", trojan" {
+				t.Errorf("got enable %v limit %d expiry %d reset %d note %q", got.Enable, got.LimitIP, got.ExpiryTime, got.ResetDays, got.Note)
+			}
+			if id := got.ProxySettings["vless"].(map[string]interface{})["id"]; id != "a" {
+				t.Errorf("vless id = %v, the first user's credentials must be kept", id)
+			}
+			if _, ok := got.ProxySettings["trojan"]; !ok {
+				t.Errorf("trojan credentials were not merged")
+			}
+		})
+	}
+}
+
+func TestLaterExpiry(t *testing.T) {
+	tests := []struct {
+		a, b, want int64
+	}{
+		{1000, 2000, 2000},
+		{2000, 1000, 2000},
+		{0, 2000, 0},
+		{2000, 0, 0},
+		{-5000, 2000, 2000},
+		{2000, -5000, 2000},
+		{-5000, -9000, -9000},
+		{-9000, -5000, -9000},
+	}
+	for _, tt := range tests {
+		if got := laterExpiry(tt.a, tt.b); got != tt.want {
+			t.Errorf("laterExpiry(%d, %d) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func copySettings(settings map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(settings))
+	for k, v := range settings {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,214 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// Strategies for an imported inbound whose port or tag is already taken on the target.
+const (
+	ConflictReplace         = "replace"      // the file's inbound replaces the target's, clients only on the target are removed
+	ConflictMergeFileWins   = "merge-file"   // union of both client lists, the file's values win for shared clients
+	ConflictMergeTargetWins = "merge-target" // union of both client lists, the target's values win for shared clients
+	ConflictSkip            = "skip"         // the target inbound is left alone
+	ConflictNewPort         = "new-port"     // the inbound is created on the next free port
+)
+
+// conflictResolver asks for a strategy per conflicting inbound, until one is chosen for all.
+type conflictResolver struct {
+	remembered string
+}
+
+// choose returns the strategy for importing inbound over target.
+func (r *conflictResolver) choose(inbound models.InboundData, target models.Inbound, targetClients int) string {
+	if r.remembered != "" {
+		return r.remembered
+	}
+	fmt.Printf(" "+utils.ColorBrightYellow+"'%s' (%s, port %d, %d clients) conflicts with existing '%s' (id %d, port %d, tag %s, %d clients)\n"+utils.ColorReset,
+		inbound.Remark, inbound.Protocol, inbound.Port, len(inbound.Clients), target.Remark, target.ID, target.Port, target.Tag, targetClients)
+	fmt.Println(" \t[1] Replace the existing inbound, clients only on the target are removed (default)")
+	fmt.Println(" \t[2] Merge clients, values from the file win")
+	fmt.Println(" \t[3] Merge clients, values on the target win")
+	fmt.Println(" \t[4] Skip this inbound")
+	fmt.Println(" \t[5] Create it on the next free port")
+	strategy := map[string]string{
+		"2": ConflictMergeFileWins,
+		"3": ConflictMergeTargetWins,
+		"4": ConflictSkip,
+		"5": ConflictNewPort,
+	}[PromptForInputStyled("Select [1]", " ➜", utils.ColorBrightYellow)]
+	if strategy == "" {
+		strategy = ConflictReplace
+	}
+	if strings.ToLower(PromptForInputStyled("Use this choice for all remaining conflicts? (y/N)", " ➜", utils.ColorBrightYellow)) == "y" {
+		r.remembered = strategy
+	}
+	return strategy
+}
+
+// targetClients decodes the clients and accounts of an inbound on the target panel.
+func targetClients(target models.Inbound) ([]models.ClientDetails, []models.Account) {
+	var settings models.InboundSettings
+	if strings.TrimSpace(target.Settings) == "" || json.Unmarshal([]byte(target.Settings), &settings) != nil {
+		return nil, nil
+	}
+	details := make([]models.ClientDetails, 0, len(settings.Clients))
+	for _, cs := range settings.Clients {
+		details = append(details, clients.ClientDetailsFromSetting(cs))
+	}
+	return details, settings.Accounts
+}
+
+// clientMergeKeys returns the keys a client is matched on: its credential (UUID, or password for
+// trojan and shadowsocks) and its email.
+func clientMergeKeys(c models.ClientDetails) []string {
+	var keys []string
+	if c.ClientID != "" {
+		keys = append(keys, "id:"+strings.ToLower(c.ClientID))
+	} else if c.ClientPassword != "" {
+		keys = append(keys, "pw:"+c.ClientPassword)
+	}
+	if c.ClientEmail != "" {
+		keys = append(keys, "email:"+strings.ToLower(c.ClientEmail))
+	}
+	return keys
+}
+
+// mergeInbound merges the target's clients (and accounts) into the file's inbound. Clients
+// matching by UUID/password or email are taken from the file when fileWins, otherwise from
+// the target; clients present on one side only are kept.
+func mergeInbound(inbound *models.InboundData, target models.Inbound, fileWins bool) {
+	targetDetails, targetAccounts := targetClients(target)
+
+	merged := append([]models.ClientDetails{}, inbound.Clients...)
+	index := make(map[string]int)
+	for i, c := range merged {
+		for _, key := range clientMergeKeys(c) {
+			index[key] = i
+		}
+	}
+	added, shared := 0, 0
+	for _, tc := range targetDetails {
+		pos, found := -1, false
+		for _, key := range clientMergeKeys(tc) {
+			if pos, found = index[key]; found {
+				break
+			}
+		}
+		if !found {
+			for _, key := range clientMergeKeys(tc) {
+				index[key] = len(merged)
+			}
+			merged = append(merged, tc)
+			added++
+			continue
+		}
+		shared++
+		if !fileWins {
+			merged[pos] = tc
+		}
+	}
+	inbound.Clients = merged
+
+	accounts := append([]models.Account{}, inbound.Accounts...)
+	byUser := make(map[string]int)
+	for i, a := range accounts {
+		byUser[a.User] = i
+	}
+	for _, ta := range targetAccounts {
+		if pos, found := byUser[ta.User]; found {
+			if !fileWins {
+				accounts[pos] = ta
+			}
+			continue
+		}
+		byUser[ta.User] = len(accounts)
+		accounts = append(accounts, ta)
+	}
+	inbound.Accounts = accounts
+
+	fmt.Printf(" "+utils.ColorCyan+"⇄ Merged: %d client(s) kept from the target, %d shared\n"+utils.ColorReset, added, shared)
+}
+
+// mergesPerClient reports whether a protocol's clients can be merged through the client-level
+// endpoints, which leave the inbound and the traffic counters of untouched clients alone.
+func mergesPerClient(protocol string) bool {
+	switch protocol {
+	case "vmess", "vless", "trojan", "shadowsocks":
+		return true
+	}
+	return false
+}
+
+// mergeClients merges the file's clients into the target inbound one client at a time: clients
+// missing on the target are added, and shared clients are updated when fileWins. Clients only on
+// the target and the inbound itself are not touched. The clients that were written are returned.
+// Every client is checked against the target inbound before anything is sent.
+func mergeClients(client *clients.ThreeXUIClient, inbound models.InboundData, target models.Inbound, fileWins bool) (added, updated []models.ClientDetails, err error) {
+	raws := make([]map[string]interface{}, len(inbound.Clients))
+	for i, fc := range inbound.Clients {
+		if raws[i], err = targetClientMap(inbound.Protocol, target, fc); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	targetDetails, _ := targetClients(target)
+	index := make(map[string]models.ClientDetails)
+	for _, tc := range targetDetails {
+		for _, key := range clientMergeKeys(tc) {
+			index[key] = tc
+		}
+	}
+
+	var toAdd []models.ClientDetails
+	var toAddRaw []map[string]interface{}
+	for i, fc := range inbound.Clients {
+		var shared *models.ClientDetails
+		for _, key := range clientMergeKeys(fc) {
+			if tc, found := index[key]; found {
+				shared = &tc
+				break
+			}
+		}
+		if shared == nil {
+			toAdd = append(toAdd, fc)
+			toAddRaw = append(toAddRaw, raws[i])
+			continue
+		}
+		if !fileWins {
+			continue
+		}
+		clientKey := clients.ClientKey(inbound.Protocol, clients.ClientMap(*shared))
+		if err := client.UpdateClient(target.ID, clientKey, raws[i]); err != nil {
+			return added, updated, fmt.Errorf("could not update client %s: %v", fc.ClientEmail, err)
+		}
+		updated = append(updated, fc)
+	}
+	if len(toAdd) > 0 {
+		if err := client.AddClients(target.ID, toAddRaw); err != nil {
+			return added, updated, fmt.Errorf("could not add %d client(s): %v", len(toAdd), err)
+		}
+		added = toAdd
+	}
+	return added, updated, nil
+}
+
+// targetClientMap builds the raw client object for a file client merged into target. Shadowsocks
+// clients get the same method and 2022 key checks as a created inbound, against the target's method.
+func targetClientMap(protocol string, target models.Inbound, fc models.ClientDetails) (map[string]interface{}, error) {
+	if protocol != "shadowsocks" {
+		return clients.ClientMap(fc), nil
+	}
+	var settings struct {
+		Method string `json:"method"`
+	}
+	if err := json.Unmarshal([]byte(target.Settings), &settings); err != nil || settings.Method == "" {
+		return nil, fmt.Errorf("target inbound '%s' has no shadowsocks method", target.Remark)
+	}
+	return clients.ShadowsocksClient(settings.Method, fc)
+}
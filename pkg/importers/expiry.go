@@ -0,0 +1,127 @@
+package importers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"panels_user_manager/pkg/utils"
+)
+
+const secondsPerDay = 86400
+
+// ExpiryRules transform user expiries at import time, e.g. to compensate for migration downtime.
+type ExpiryRules struct {
+	ShiftDays          int   // added to every expiry and delayed-start duration, may be negative
+	MinRemainingDays   int   // expiries closer than this (or already past) are moved to now + MinRemainingDays
+	NoExpiryDate       int64 // unix seconds given to users without expiry; 0 keeps them never-expiring
+	DelayedStartOnHold bool  // import 3X-UI delayed-start users as PasarGuard on_hold instead of a fixed date
+	OnHoldTimeoutDays  int   // on_hold users start expiring after this many days even without connecting; 0 = never
+}
+
+// ExpiryResult is the transformed expiry of one user.
+type ExpiryResult struct {
+	Expire       int64 // unix seconds, 0 = never
+	DelayedStart int64 // seconds of validity counted from first use; when set, Expire is 0
+}
+
+// Apply transforms a raw expiry from an export file. Positive values are unix timestamps in
+// seconds or milliseconds; negative values are 3X-UI delayed-start durations in milliseconds.
+func (r ExpiryRules) Apply(expiry int64, now time.Time) ExpiryResult {
+	shift := int64(r.ShiftDays) * secondsPerDay
+	minRemaining := int64(r.MinRemainingDays) * secondsPerDay
+
+	if expiry < 0 {
+		duration := -expiry/1000 + shift
+		if duration < minRemaining {
+			duration = minRemaining
+		}
+		if duration < secondsPerDay {
+			duration = secondsPerDay
+		}
+		return ExpiryResult{DelayedStart: duration}
+	}
+	if expiry == 0 {
+		return ExpiryResult{Expire: r.NoExpiryDate}
+	}
+
+	if expiry > 1e11 {
+		expiry = expiry / 1000
+	}
+	expiry += shift
+	if minRemaining > 0 && expiry < now.Unix()+minRemaining {
+		expiry = now.Unix() + minRemaining
+	}
+	return ExpiryResult{Expire: expiry}
+}
+
+// String describes the rules for the import summary.
+func (r ExpiryRules) String() string {
+	var parts []string
+	if r.ShiftDays != 0 {
+		parts = append(parts, fmt.Sprintf("shift %+d days", r.ShiftDays))
+	}
+	if r.MinRemainingDays > 0 {
+		parts = append(parts, fmt.Sprintf("at least %d days remaining", r.MinRemainingDays))
+	}
+	if r.NoExpiryDate > 0 {
+		parts = append(parts, "no expiry → "+time.Unix(r.NoExpiryDate, 0).Format("2006-01-02"))
+	}
+	if r.DelayedStartOnHold {
+		parts = append(parts, "delayed start → on hold")
+		if r.OnHoldTimeoutDays > 0 {
+			parts = append(parts, fmt.Sprintf("on hold timeout %d days", r.OnHoldTimeoutDays))
+		}
+	}
+	if len(parts) == 0 {
+		return "unchanged"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// PromptExpiryRules asks for the expiry transformations to apply. The on_hold question is only
+// asked when importing into PasarGuard; 3X-UI keeps delayed starts natively.
+func PromptExpiryRules(askOnHold bool) ExpiryRules {
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Expiry rules (press Enter to keep expiries unchanged):" + utils.ColorReset)
+	rules := ExpiryRules{
+		ShiftDays:        promptInt("Shift all expiries by N days [0]", true),
+		MinRemainingDays: promptInt("Minimum remaining lifetime in days [0]", false),
+	}
+	for {
+		input := PromptForInputStyled("Fixed expiry date for users without expiry (YYYY-MM-DD) [none]", " ➜", utils.ColorBrightYellow)
+		if input == "" {
+			break
+		}
+		date, err := time.ParseInLocation("2006-01-02", input, time.Local)
+		if err == nil {
+			rules.NoExpiryDate = date.Unix()
+			break
+		}
+		fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Invalid date '%s'\n"+utils.ColorReset, input)
+	}
+	if askOnHold {
+		answer := strings.ToLower(PromptForInputStyled("Import delayed-start users as on hold (start on first use)? (Y/n)", " ➜", utils.ColorBrightYellow))
+		rules.DelayedStartOnHold = answer != "n"
+		if rules.DelayedStartOnHold {
+			rules.OnHoldTimeoutDays = promptInt("Start expiring on-hold users that have not connected after N days [never]", false)
+		}
+	}
+	fmt.Printf(" "+utils.ColorBrightGreen+"✓ Expiry rules: %s\n"+utils.ColorReset, rules)
+	return rules
+}
+
+// promptInt reads an integer, repeating the question until the input is valid. Empty input is 0.
+func promptInt(label string, allowNegative bool) int {
+	for {
+		input := PromptForInputStyled(label, " ➜", utils.ColorBrightYellow)
+		if input == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(input)
+		if err == nil && (allowNegative || n >= 0) {
+			return n
+		}
+		fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Invalid number '%s'\n"+utils.ColorReset, input)
+	}
+}
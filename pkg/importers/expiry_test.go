@@ -0,0 +1,41 @@
+package importers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryRulesApply(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := int64(secondsPerDay)
+	in30 := now.Unix() + 30*day
+	tests := []struct {
+		name   string
+		rules  ExpiryRules
+		expiry int64
+		want   ExpiryResult
+	}{
+		{"unchanged seconds", ExpiryRules{}, in30, ExpiryResult{Expire: in30}},
+		{"milliseconds are converted", ExpiryRules{}, in30 * 1000, ExpiryResult{Expire: in30}},
+		{"never expiring", ExpiryRules{}, 0, ExpiryResult{}},
+		{"never expiring gets a date", ExpiryRules{NoExpiryDate: in30}, 0, ExpiryResult{Expire: in30}},
+		{"shift", ExpiryRules{ShiftDays: 3}, in30, ExpiryResult{Expire: in30 + 3*day}},
+		{"negative shift", ExpiryRules{ShiftDays: -3}, in30, ExpiryResult{Expire: in30 - 3*day}},
+		{"minimum remaining moves close expiry", ExpiryRules{MinRemainingDays: 60}, in30, ExpiryResult{Expire: now.Unix() + 60*day}},
+		{"minimum remaining moves past expiry", ExpiryRules{MinRemainingDays: 7}, now.Unix() - 10*day, ExpiryResult{Expire: now.Unix() + 7*day}},
+		{"minimum remaining keeps later expiry", ExpiryRules{MinRemainingDays: 7}, in30, ExpiryResult{Expire: in30}},
+		{"past expiry stays past", ExpiryRules{}, now.Unix() - 10*day, ExpiryResult{Expire: now.Unix() - 10*day}},
+
+		{"delayed start", ExpiryRules{}, -30 * day * 1000, ExpiryResult{DelayedStart: 30 * day}},
+		{"delayed start shifted", ExpiryRules{ShiftDays: 5}, -30 * day * 1000, ExpiryResult{DelayedStart: 35 * day}},
+		{"delayed start never below a day", ExpiryRules{ShiftDays: -40}, -30 * day * 1000, ExpiryResult{DelayedStart: day}},
+		{"delayed start minimum remaining", ExpiryRules{MinRemainingDays: 45}, -30 * day * 1000, ExpiryResult{DelayedStart: 45 * day}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rules.Apply(tt.expiry, now); got != tt.want {
+				t.Errorf("Apply(%d) = %+v, want %+v", tt.expiry, got, tt.want)
+			}
+		})
+	}
+}
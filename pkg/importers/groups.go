@@ -0,0 +1,328 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/storage"
+	"panels_user_manager/pkg/utils"
+)
+
+// GroupRule assigns groups to users of the 3X-UI inbounds it matches. Every field that is set
+// must match; remark and tag are compared case-insensitively.
+type GroupRule struct {
+	Remark string   `json:"remark,omitempty"`
+	Tag    string   `json:"tag,omitempty"`
+	Port   int      `json:"port,omitempty"`
+	Groups []string `json:"groups"` // group names or numeric IDs
+}
+
+// GroupMapping decides the PasarGuard groups of imported users. The first matching rule wins;
+// users matching no rule get the default groups.
+type GroupMapping struct {
+	Rules   []GroupRule `json:"rules"`
+	Default []string    `json:"default,omitempty"`
+}
+
+// resolvedGroupMapping is a GroupMapping with group names replaced by panel group IDs.
+type resolvedGroupMapping struct {
+	rules    []GroupRule
+	ruleIDs  [][]int
+	defaults []int
+}
+
+// Matches reports whether the rule applies to a user exported from the given inbound.
+func (r GroupRule) Matches(user models.PasarGuardUser) bool {
+	if r.Remark == "" && r.Tag == "" && r.Port == 0 {
+		return false
+	}
+	return (r.Remark == "" || strings.EqualFold(r.Remark, user.Remark)) &&
+		(r.Tag == "" || strings.EqualFold(r.Tag, user.InboundTag)) &&
+		(r.Port == 0 || r.Port == user.Port)
+}
+
+// describe names the inbound the rule matches, for prompts and summaries.
+func (r GroupRule) describe() string {
+	var parts []string
+	if r.Remark != "" {
+		parts = append(parts, fmt.Sprintf("'%s'", r.Remark))
+	}
+	if r.Tag != "" {
+		parts = append(parts, "tag "+r.Tag)
+	}
+	if r.Port != 0 {
+		parts = append(parts, fmt.Sprintf("port %d", r.Port))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// LoadGroupMapping reads a mapping file.
+func LoadGroupMapping(filename string) (*GroupMapping, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading group mapping: %v", err)
+	}
+	mapping := &GroupMapping{}
+	if err := json.Unmarshal(data, mapping); err != nil {
+		return nil, fmt.Errorf("error parsing group mapping '%s': %v", filename, err)
+	}
+	return mapping, nil
+}
+
+// SaveGroupMapping writes a mapping file that can be loaded again in later imports.
+func SaveGroupMapping(filename string, mapping *GroupMapping) error {
+	data, err := json.MarshalIndent(mapping, "", " ")
+	if err != nil {
+		return fmt.Errorf("error creating group mapping JSON: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0644); err != nil {
+		return fmt.Errorf("error saving group mapping: %v", err)
+	}
+	return nil
+}
+
+// Resolve looks up every group reference on the panel. References that match no group by ID or
+// name are returned as missing and left out of the result.
+func (m *GroupMapping) Resolve(groups []models.PasarGuardGroup) (*resolvedGroupMapping, []string) {
+	var missing []string
+	seenMissing := make(map[string]bool)
+	lookup := func(refs []string) []int {
+		ids := []int{}
+		for _, ref := range refs {
+			if id, ok := findGroup(groups, ref); ok {
+				ids = append(ids, id)
+			} else if !seenMissing[ref] {
+				seenMissing[ref] = true
+				missing = append(missing, ref)
+			}
+		}
+		return ids
+	}
+	resolved := &resolvedGroupMapping{rules: m.Rules, defaults: lookup(m.Default)}
+	for _, rule := range m.Rules {
+		resolved.ruleIDs = append(resolved.ruleIDs, lookup(rule.Groups))
+	}
+	return resolved, missing
+}
+
+// groupsFor returns the group IDs for a user.
+func (r *resolvedGroupMapping) groupsFor(user models.PasarGuardUser) []int {
+	for i, rule := range r.rules {
+		if rule.Matches(user) {
+			return r.ruleIDs[i]
+		}
+	}
+	if r.defaults == nil {
+		return []int{}
+	}
+	return r.defaults
+}
+
+// findGroup resolves a group reference: a numeric ID of an existing group, or a group name.
+func findGroup(groups []models.PasarGuardGroup, ref string) (int, bool) {
+	ref = strings.TrimSpace(ref)
+	if id, err := strconv.Atoi(ref); err == nil {
+		for _, g := range groups {
+			if g.ID == id {
+				return id, true
+			}
+		}
+	}
+	for _, g := range groups {
+		if strings.EqualFold(g.Name, ref) {
+			return g.ID, true
+		}
+	}
+	return 0, false
+}
+
+// PromptGroupMapping asks how imported users are assigned to groups: the same groups for
+// everyone, per inbound of the export file, or from a mapping file.
+func PromptGroupMapping(groups []models.PasarGuardGroup, filePath string, opts storage.Options) *GroupMapping {
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Available Groups:" + utils.ColorReset)
+	for i, g := range groups {
+		fmt.Printf(" \t[%d] %s (id=%d)\n", i+1, g.Name, g.ID)
+	}
+	if len(groups) == 0 {
+		fmt.Println(" \t(none yet — type group names to create them)")
+	}
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Group assignment:" + utils.ColorReset)
+	fmt.Println(" \t[1] Same groups for every user (default)")
+	fmt.Println(" \t[2] Choose groups per 3X-UI inbound")
+	fmt.Println(" \t[3] Load an inbound-to-group mapping file")
+
+	mapping := &GroupMapping{}
+	switch PromptForInputStyled("Select [1]", " ➜", utils.ColorBrightYellow) {
+	case "2":
+		inbounds, err := fileInbounds(filePath, opts)
+		if err != nil {
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Could not list inbounds: %v\n"+utils.ColorReset, err)
+		}
+		if len(inbounds) == 0 {
+			fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " The file has no inbound information; falling back to default groups")
+		}
+		for _, inbound := range inbounds {
+			inbound.Groups = promptGroupSelection(fmt.Sprintf("Group number(s) or new name(s) for inbound %s, or Enter for the default", inbound.describe()), groups)
+			if len(inbound.Groups) > 0 {
+				mapping.Rules = append(mapping.Rules, inbound)
+			}
+		}
+		mapping.Default = promptGroupSelection("Default group number(s) or new name(s) for users of other inbounds, or Enter for none", groups)
+		if path := PromptForInputStyled("Save this mapping to a file for later imports? (path, or Enter to skip)", " ➜", utils.ColorBrightYellow); path != "" {
+			if err := SaveGroupMapping(path, mapping); err != nil {
+				fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ %v\n"+utils.ColorReset, err)
+			} else {
+				fmt.Printf(" "+utils.ColorBrightGreen+"✓ Mapping saved to %s\n"+utils.ColorReset, path)
+			}
+		}
+	case "3":
+		for {
+			path := PromptForInputStyled("Path to the mapping file", " ➜", utils.ColorBrightYellow)
+			loaded, err := LoadGroupMapping(path)
+			if err == nil {
+				mapping = loaded
+				break
+			}
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ %v\n"+utils.ColorReset, err)
+		}
+	default:
+		mapping.Default = promptGroupSelection("Select group number(s) or new name(s) to assign to imported users (comma-separated), or press Enter to skip", groups)
+	}
+
+	if len(mapping.Rules) == 0 && len(mapping.Default) == 0 {
+		fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " No group assignment selected (skipping)")
+	} else {
+		fmt.Printf(" "+utils.ColorBrightGreen+"✓ Group mapping: %d inbound rule(s), default groups %v\n"+utils.ColorReset, len(mapping.Rules), mapping.Default)
+	}
+	return mapping
+}
+
+// promptGroupSelection reads comma-separated group numbers from the list shown to the user and
+// returns the selected groups' IDs as references. Non-numeric entries are kept as group names,
+// which can be created before the import starts.
+func promptGroupSelection(label string, groups []models.PasarGuardGroup) []string {
+	refs := []string{}
+	for _, p := range strings.Split(PromptForInputStyled(label, " ➜", utils.ColorBrightYellow), ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		idx, err := strconv.Atoi(p)
+		if err != nil {
+			refs = append(refs, p)
+			continue
+		}
+		if idx < 1 || idx > len(groups) {
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Selection '%d' out of range, skipping\n"+utils.ColorReset, idx)
+			continue
+		}
+		refs = append(refs, strconv.Itoa(groups[idx-1].ID))
+	}
+	return refs
+}
+
+// CreateMissingGroups offers to create the groups a mapping references by name but the panel
+// does not have yet, each with inbound tags chosen from the panel. It reports whether any group
+// was created.
+func CreateMissingGroups(client *clients.PasarGuardClient, missing []string) bool {
+	var names []string
+	for _, ref := range missing {
+		if _, err := strconv.Atoi(ref); err == nil {
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ No group with ID %s exists; only named groups can be created\n"+utils.ColorReset, ref)
+			continue
+		}
+		names = append(names, ref)
+	}
+	if len(names) == 0 {
+		return false
+	}
+	fmt.Printf("\n "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorBrightCyan+"Groups not found on the panel: %s\n"+utils.ColorReset, strings.Join(names, ", "))
+	if strings.ToLower(PromptForInputStyled("Create them now? (y/N)", " ➜", utils.ColorBrightYellow)) != "y" {
+		return false
+	}
+
+	tags, err := client.GetInboundTags()
+	if err != nil {
+		fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Could not fetch inbound tags: %v\n"+utils.ColorReset, err)
+	}
+	if len(tags) > 0 {
+		fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Available inbound tags:" + utils.ColorReset)
+		for i, tag := range tags {
+			fmt.Printf(" \t[%d] %s\n", i+1, tag)
+		}
+	}
+
+	created := false
+	for _, name := range names {
+		groupTags := tags
+		if len(tags) > 0 {
+			groupTags = promptTagSelection(fmt.Sprintf("Inbound tag number(s) for group '%s', or Enter for all", name), tags)
+		}
+		group, err := client.CreateGroup(name, groupTags)
+		if err != nil {
+			fmt.Printf(" "+utils.ColorRed+"✗ Could not create group '%s': %v\n"+utils.ColorReset, name, err)
+			continue
+		}
+		created = true
+		fmt.Printf(" "+utils.ColorBrightGreen+"✓ Created group '%s' (id=%d) with %d inbound tag(s)\n"+utils.ColorReset, group.Name, group.ID, len(groupTags))
+	}
+	return created
+}
+
+// promptTagSelection reads comma-separated numbers from the tag list; empty input selects every tag.
+func promptTagSelection(label string, tags []string) []string {
+	for {
+		input := PromptForInputStyled(label, " ➜", utils.ColorBrightYellow)
+		if input == "" {
+			return tags
+		}
+		var selected []string
+		valid := true
+		for _, p := range strings.Split(input, ",") {
+			idx, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || idx < 1 || idx > len(tags) {
+				fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Invalid selection '%s'\n"+utils.ColorReset, strings.TrimSpace(p))
+				valid = false
+				break
+			}
+			selected = append(selected, tags[idx-1])
+		}
+		if valid {
+			return selected
+		}
+	}
+}
+
+// fileInbounds lists the distinct 3X-UI inbounds the users of an export file came from.
+func fileInbounds(filePath string, opts storage.Options) ([]GroupRule, error) {
+	records, err := storage.OpenRecords[models.PasarGuardUser](filePath, opts, storage.KindUsers)
+	if err != nil {
+		return nil, err
+	}
+	defer records.Close()
+	var inbounds []GroupRule
+	seen := make(map[string]bool)
+	for {
+		user, err := records.Next()
+		if err == io.EOF {
+			return inbounds, nil
+		}
+		if err != nil {
+			return inbounds, err
+		}
+		if user.Remark == "" && user.InboundTag == "" && user.Port == 0 {
+			continue
+		}
+		key := fmt.Sprintf("%s|%s|%d", strings.ToLower(user.Remark), strings.ToLower(user.InboundTag), user.Port)
+		if !seen[key] {
+			seen[key] = true
+			inbounds = append(inbounds, GroupRule{Remark: user.Remark, Tag: user.InboundTag, Port: user.Port})
+		}
+	}
+}
@@ -1,17 +1,22 @@
 package importers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"strconv"
 	"strings"
+	"time"
 
 	"panels_user_manager/pkg/clients"
 	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/storage"
 	"panels_user_manager/pkg/utils"
 )
 
+// ForceImport allows importing files that do not match their integrity manifest.
+var ForceImport = false
+
 // ImportFromJSON handles the process of importing 3X-UI inbounds from a file.
 // Similar to PasarGuard import: checks for UUID conflicts and updates existing users.
 func ImportFromJSON(client *clients.ThreeXUIClient) {
@@ -23,27 +28,43 @@ func ImportFromJSON(client *clients.ThreeXUIClient) {
 	fmt.Println(" " + utils.ColorBrightBlue + "┌─────────────────────────────────────────────────────────────────┐" + utils.ColorReset)
 	// 1. Read the file content
 	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " [1/4] " + utils.ColorBrightGreen + "Reading JSON file..." + utils.ColorReset)
-	fileBytes, err := os.ReadFile(filePath)
+	readOpts, err := DecryptionOptions(filePath)
+	if err != nil {
+		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
+		utils.PrintError(fmt.Sprintf("Error reading file '%s': %v", filePath, err))
+		return
+	}
+	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 		utils.PrintError(fmt.Sprintf("Error reading file '%s': %v", filePath, err))
 		return
 	}
-	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ File loaded (%d bytes)\n", len(fileBytes))
-	// 2. Parse the JSON
+	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ File opened (%d bytes)\n", fileInfo.Size())
+	if !verifyExportIntegrity(filePath, readOpts) {
+		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
+		utils.PrintError("Import aborted: file does not match its manifest (use -force to override)")
+		return
+	}
+	// 2. Parse the JSON header; inbounds are decoded one at a time while importing
 	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " [2/4] " + utils.ColorBrightGreen + "Parsing JSON content..." + utils.ColorReset)
-	var dataToImport models.OutputFile
-	if err := json.Unmarshal(fileBytes, &dataToImport); err != nil {
+	records, err := storage.OpenRecords[models.InboundData](filePath, readOpts, storage.KindInbounds)
+	if err != nil {
 		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 		utils.PrintError(fmt.Sprintf("Error parsing JSON file. Make sure it's a valid export file: %v", err))
 		return
 	}
-	if len(dataToImport.Inbounds) == 0 {
+	defer records.Close()
+	header := records.Header()
+	if !records.More() {
 		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 		utils.PrintWarning("No inbounds found in the file to import")
 		return
 	}
-	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ Found %d inbound(s) to import\n", len(dataToImport.Inbounds))
+	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ Found %d inbound(s) to import\n", header.TotalInbounds)
+	trafficPolicy := PromptTrafficPolicy(" (usage is restored after the import)")
+	expiryRules := PromptExpiryRules(false)
+	remap := PromptPortRemap()
 
 	// 3. Fetch existing inbounds to check for conflicts
 	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " [3/4] " + utils.ColorBrightGreen + "Checking for conflicts..." + utils.ColorReset)
@@ -57,7 +78,9 @@ func ImportFromJSON(client *clients.ThreeXUIClient) {
 	// Build map of existing inbound ports and tags with their IDs
 	existingPorts := make(map[int]int)   // port -> inbound ID
 	existingTags := make(map[string]int) // tag -> inbound ID
+	existingByID := make(map[int]models.Inbound)
 	for _, ib := range existingInbounds {
+		existingByID[ib.ID] = ib
 		existingPorts[ib.Port] = ib.ID
 		existingTags[ib.Tag] = ib.ID
 	}
@@ -68,32 +91,53 @@ func ImportFromJSON(client *clients.ThreeXUIClient) {
 	successCount := 0
 	failureCount := 0
 	updateCount := 0
+	skipCount := 0
+	conflicts := &conflictResolver{}
+	usage := &usageRestore{}
+
+	// 4. Decode each inbound from the file and create/update it on the panel
+	processedCount := 0
+	wgPlan := &wireGuardPlan{}
+	for idx := 0; ; idx++ {
+		inbound, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Error reading inbound #%d from file: %v", idx+1, err))
+			failureCount++
+			break
+		}
+		processedCount++
+		applyTrafficPolicy(&inbound, trafficPolicy)
+		applyExpiryRules(&inbound, expiryRules, time.Now())
+		if inbound.Protocol == "wireguard" && !wgPlan.asked {
+			wgPlan.prompt(client.BaseURL)
+		}
+
+		fmt.Printf("\n " + utils.ColorBrightBlue + "════════════════════════════════════════════════════════════════════════\n" + utils.ColorReset)
+		fmt.Printf(" "+utils.ColorBrightYellow+"[%d/%d] Processing: %s (Port: %d)\n"+utils.ColorReset, idx+1, header.TotalInbounds, inbound.Remark, inbound.Port)
 
-	// 4. حجم کاربران را بر اساس traffic_remaining تنظیم کنید
-	// منطق: حجم کاربر = حجم باقیمانده (traffic_remaining)
-	for idx := range dataToImport.Inbounds {
-		for jdx := range dataToImport.Inbounds[idx].Clients {
-			client := &dataToImport.Inbounds[idx].Clients[jdx]
-			// اگر traffic_remaining موجود و مثبت باشد، آن را به عنوان حجم کل استفاده کنید
-			if client.TrafficRemaining > 0 {
-				client.ClientTotalGB = client.TrafficRemaining
-			} else if client.TrafficRemaining == 0 {
-				// اگر باقی نیست، حجم را 0 (unlimited) سیٹ کنید
-				client.ClientTotalGB = 0
+		wgPublicKey := ""
+		if inbound.Protocol == "wireguard" && wgPlan.rotate {
+			if wgPublicKey, err = wgPlan.rotateKeys(&inbound); err != nil {
+				fmt.Printf(" "+utils.ColorBrightRed+"❌ FAILED: %v\n"+utils.ColorReset, err)
+				failureCount++
+				fmt.Println(" " + utils.ColorBrightBlue + "════════════════════════════════════════════════════════════════════════" + utils.ColorReset)
+				continue
 			}
-			// اگر TrafficRemaining منفی باشد، ClientTotalGB بدون تغییر باقی می‌ماند
 		}
-	}
 
-	// 5. Loop through each inbound and create/update it on the panel
-	for idx, inbound := range dataToImport.Inbounds {
-		fmt.Printf("\n " + utils.ColorBrightBlue + "════════════════════════════════════════════════════════════════════════\n" + utils.ColorReset)
-		fmt.Printf(" "+utils.ColorBrightYellow+"[%d/%d] Processing: %s (Port: %d)\n"+utils.ColorReset, idx+1, len(dataToImport.Inbounds), inbound.Remark, inbound.Port)
+		remap.Apply(&inbound, existingPorts)
 
 		// Check if port or tag already exists
 		existingID, portExists := existingPorts[inbound.Port]
 		existingTagID, tagExists := existingTags[inbound.Tag]
-
+		// Use the existing ID to update
+		updateID := existingID
+		if tagExists {
+			updateID = existingTagID
+		}
 		if portExists || tagExists {
 			fmt.Printf(" " + utils.ColorBrightYellow + "⚠️  Conflict detected (Port/Tag already exists)\n" + utils.ColorReset)
 			if utils.VerboseMode {
@@ -101,12 +145,57 @@ func ImportFromJSON(client *clients.ThreeXUIClient) {
 				fmt.Printf(" "+utils.ColorCyan+"Protocol: %s | Clients: %d\n"+utils.ColorReset, inbound.Protocol, len(inbound.Clients))
 			}
 
-			// Use the existing ID to update
-			updateID := existingID
-			if tagExists {
-				updateID = existingTagID
+			target, found := existingByID[updateID]
+			if !found {
+				fmt.Printf(" " + utils.ColorBrightRed + "❌ FAILED: conflicts with an inbound created in this import whose ID could not be looked up\n" + utils.ColorReset)
+				failureCount++
+				fmt.Println(" " + utils.ColorBrightBlue + "════════════════════════════════════════════════════════════════════════" + utils.ColorReset)
+				continue
 			}
+			targetDetails, _ := targetClients(target)
 
+			strategy := conflicts.choose(inbound, target, len(targetDetails))
+			if (strategy == ConflictMergeFileWins || strategy == ConflictMergeTargetWins) && inbound.Protocol == "wireguard" {
+				fmt.Printf(" " + utils.ColorBrightYellow + "⚠️  WireGuard peers cannot be merged, replacing instead\n" + utils.ColorReset)
+				strategy = ConflictReplace
+			}
+			switch strategy {
+			case ConflictSkip:
+				fmt.Printf(" " + utils.ColorBrightYellow + "⏭ Skipped, existing inbound left unchanged\n" + utils.ColorReset)
+				skipCount++
+				fmt.Println(" " + utils.ColorBrightBlue + "════════════════════════════════════════════════════════════════════════" + utils.ColorReset)
+				continue
+			case ConflictNewPort:
+				if err := moveToFreePort(&inbound, existingPorts, existingTags); err != nil {
+					fmt.Printf(" "+utils.ColorBrightRed+"❌ FAILED: %v\n"+utils.ColorReset, err)
+					failureCount++
+					fmt.Println(" " + utils.ColorBrightBlue + "════════════════════════════════════════════════════════════════════════" + utils.ColorReset)
+					continue
+				}
+				portExists, tagExists = false, false
+			case ConflictMergeFileWins, ConflictMergeTargetWins:
+				if !mergesPerClient(inbound.Protocol) {
+					mergeInbound(&inbound, target, strategy == ConflictMergeFileWins)
+					break
+				}
+				added, updated, err := mergeClients(client, inbound, target, strategy == ConflictMergeFileWins)
+				if trafficPolicy.Mode == TrafficKeepTotal {
+					usage.add(target.ID, inbound.Protocol, added)
+					usage.add(target.ID, inbound.Protocol, updated)
+				}
+				if err != nil {
+					fmt.Printf(" "+utils.ColorBrightRed+"❌ MERGE FAILED: %v\n"+utils.ColorReset, err)
+					failureCount++
+				} else {
+					fmt.Printf(" "+utils.ColorBrightCyan+"✅ SUCCESS (Merged: %d client(s) added, %d updated)\n"+utils.ColorReset, len(added), len(updated))
+					updateCount++
+				}
+				fmt.Println(" " + utils.ColorBrightBlue + "════════════════════════════════════════════════════════════════════════" + utils.ColorReset)
+				continue
+			}
+		}
+
+		if portExists || tagExists {
 			fmt.Printf(" " + utils.ColorBrightYellow + "↻ Attempting to update existing inbound...\n" + utils.ColorReset)
 			err := client.UpdateInbound(updateID, inbound)
 			if err != nil {
@@ -115,6 +204,12 @@ func ImportFromJSON(client *clients.ThreeXUIClient) {
 			} else {
 				fmt.Printf(" " + utils.ColorBrightCyan + "✅ SUCCESS (Updated)\n" + utils.ColorReset)
 				updateCount++
+				if trafficPolicy.Mode == TrafficKeepTotal {
+					usage.add(updateID, inbound.Protocol, inbound.Clients)
+				}
+				if wgPublicKey != "" {
+					wgPlan.writePeerConfigs(inbound, wgPublicKey)
+				}
 			}
 		} else {
 			if utils.VerboseMode {
@@ -127,12 +222,26 @@ func ImportFromJSON(client *clients.ThreeXUIClient) {
 			} else {
 				fmt.Printf(" " + utils.ColorBrightGreen + "✅ SUCCESS (Created)\n" + utils.ColorReset)
 				successCount++
-				existingPorts[inbound.Port] = inbound.ID
-				existingTags[inbound.Tag] = inbound.ID
+				// Later conflicts must go to the inbound's ID on the target, not the one in the file.
+				created, err := findCreatedInbound(client, inbound)
+				if err != nil {
+					fmt.Printf(" "+utils.ColorBrightYellow+"⚠️  Could not look up the created inbound: %v\n"+utils.ColorReset, err)
+				} else {
+					existingByID[created.ID] = created
+					existingPorts[inbound.Port] = created.ID
+					existingTags[inbound.Tag] = created.ID
+					if trafficPolicy.Mode == TrafficKeepTotal {
+						usage.add(created.ID, inbound.Protocol, inbound.Clients)
+					}
+				}
+				if wgPublicKey != "" {
+					wgPlan.writePeerConfigs(inbound, wgPublicKey)
+				}
 			}
 		}
 		fmt.Println(" " + utils.ColorBrightBlue + "════════════════════════════════════════════════════════════════════════" + utils.ColorReset)
 	}
+	usage.restore(client)
 
 	fmt.Println("\n" + utils.ColorBrightMagenta + strings.Repeat("═", 72) + utils.ColorReset)
 	fmt.Println(utils.ColorBrightMagenta + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightCyan+"📥 IMPORT SUMMARY"+utils.ColorReset, 70) + utils.ColorBrightMagenta + "║" + utils.ColorReset)
@@ -144,8 +253,52 @@ func ImportFromJSON(client *clients.ThreeXUIClient) {
 	if updateCount > 0 {
 		fmt.Printf(" "+utils.ColorYellow+"↻ Updated: %d\n"+utils.ColorReset, updateCount)
 	}
-	fmt.Printf(" "+utils.ColorCyan+"📊 Total inbounds: %d\n"+utils.ColorReset, len(dataToImport.Inbounds))
-	fmt.Printf(" "+utils.ColorBlue+"👥 Total users: %d\n\n"+utils.ColorReset, dataToImport.TotalUsers)
+	if skipCount > 0 {
+		fmt.Printf(" "+utils.ColorYellow+"⏭ Skipped (conflicts): %d\n"+utils.ColorReset, skipCount)
+	}
+	fmt.Printf(" "+utils.ColorCyan+"📊 Total inbounds: %d\n"+utils.ColorReset, processedCount)
+	fmt.Printf(" "+utils.ColorBlue+"👥 Total users: %d\n\n"+utils.ColorReset, header.TotalUsers)
+}
+
+// findCreatedInbound fetches the inbound the panel created for inbound, matched on port and tag.
+func findCreatedInbound(client *clients.ThreeXUIClient, inbound models.InboundData) (models.Inbound, error) {
+	inbounds, err := client.GetAllInbounds()
+	if err != nil {
+		return models.Inbound{}, err
+	}
+	for _, ib := range inbounds {
+		if ib.Port == inbound.Port && ib.Tag == inbound.Tag {
+			return ib, nil
+		}
+	}
+	return models.Inbound{}, fmt.Errorf("no inbound on port %d with tag %s", inbound.Port, inbound.Tag)
+}
+
+// applyTrafficPolicy sets each client's quota and status according to the carryover policy.
+// The inbound API creates clients with 0 usage: when usage is kept it is restored afterwards
+// (see usageRestore), otherwise it is folded into the quota.
+func applyTrafficPolicy(inbound *models.InboundData, policy TrafficPolicy) {
+	for jdx := range inbound.Clients {
+		client := &inbound.Clients[jdx]
+		carried := policy.Apply(client.ClientTotalGB, client.TrafficUsed, client.ClientEnable)
+		client.ClientTotalGB = carried.TotalGB
+		client.TrafficUsed = carried.UsedTraffic
+		client.ClientEnable = carried.Enable
+	}
+}
+
+// applyExpiryRules transforms each client's expiry. 3X-UI supports delayed starts natively,
+// so they stay negative millisecond durations.
+func applyExpiryRules(inbound *models.InboundData, rules ExpiryRules, now time.Time) {
+	for jdx := range inbound.Clients {
+		client := &inbound.Clients[jdx]
+		expiry := rules.Apply(client.ClientExpiryTime, now)
+		if expiry.DelayedStart > 0 {
+			client.ClientExpiryTime = -expiry.DelayedStart * 1000
+		} else {
+			client.ClientExpiryTime = expiry.Expire * 1000
+		}
+	}
 }
 
 // ImportPasarGuardUsersFromJSON handles the process of importing PasarGuard users from a file.
@@ -158,68 +311,62 @@ func ImportPasarGuardUsersFromJSON(client *clients.PasarGuardClient) {
 	fmt.Println(" " + utils.ColorBrightBlue + "┌─────────────────────────────────────────────────────────────────┐" + utils.ColorReset)
 	// 1. Read the file content
 	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " [1/3] " + utils.ColorBrightGreen + "Reading JSON file..." + utils.ColorReset)
-	fileBytes, err := os.ReadFile(filePath)
+	readOpts, err := DecryptionOptions(filePath)
+	if err != nil {
+		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
+		utils.PrintError(fmt.Sprintf("Error reading file '%s': %v", filePath, err))
+		return
+	}
+	fileInfo, err := os.Stat(filePath)
 	if err != nil {
 		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 		utils.PrintError(fmt.Sprintf("Error reading file '%s': %v", filePath, err))
 		return
 	}
-	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ File loaded (%d bytes)\n", len(fileBytes))
-	// 2. Parse the JSON
+	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ File opened (%d bytes)\n", fileInfo.Size())
+	if !verifyExportIntegrity(filePath, readOpts) {
+		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
+		utils.PrintError("Import aborted: file does not match its manifest (use -force to override)")
+		return
+	}
+	// 2. Parse the JSON header; users are decoded one at a time while importing
 	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " [2/3] " + utils.ColorBrightGreen + "Parsing JSON content..." + utils.ColorReset)
-	var dataToImport models.PasarGuardUsersExportFile
-	if err := json.Unmarshal(fileBytes, &dataToImport); err != nil {
+	records, err := storage.OpenRecords[models.PasarGuardUser](filePath, readOpts, storage.KindUsers)
+	if err != nil {
 		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 		utils.PrintError(fmt.Sprintf("Error parsing JSON file. Make sure it's a valid PasarGuard export file: %v", err))
 		return
 	}
-	if len(dataToImport.Users) == 0 {
+	defer records.Close()
+	header := records.Header()
+	if !records.More() {
 		fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 		utils.PrintWarning("No users found in the file to import")
 		return
 	}
-	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ Found %d user(s) to import\n", len(dataToImport.Users))
+	fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ Found %d user(s) to import\n", header.TotalUsers)
+	trafficPolicy := PromptTrafficPolicy("")
+	expiryRules := PromptExpiryRules(true)
 
-	// Ask user which Groups to assign imported users to
-	selectedGroupIDs := []int{}
+	// Ask how imported users are assigned to groups
+	groupMapping := &resolvedGroupMapping{}
 	groups, gErr := client.GetAllGroups()
-	if gErr == nil && len(groups) > 0 {
-		fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Available Groups:" + utils.ColorReset)
-		for i, g := range groups {
-			fmt.Printf(" \t[%d] %s (id=%d)\n", i+1, g.Name, g.ID)
-		}
-		sel := PromptForInputStyled("Select group number(s) to assign to imported users (comma-separated), or press Enter to skip", "\n ➜", utils.ColorBrightYellow)
-		sel = strings.TrimSpace(sel)
-		if sel != "" {
-			parts := strings.Split(sel, ",")
-			for _, p := range parts {
-				p = strings.TrimSpace(p)
-				if p == "" {
-					continue
-				}
-				idx, err := strconv.Atoi(p)
-				if err != nil {
-					fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Invalid number '%s', skipping\n"+utils.ColorReset, p)
-					continue
-				}
-				if idx < 1 || idx > len(groups) {
-					fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Selection '%d' out of range, skipping\n"+utils.ColorReset, idx)
-					continue
-				}
-				selectedGroupIDs = append(selectedGroupIDs, groups[idx-1].ID)
+	if gErr == nil {
+		mapping := PromptGroupMapping(groups, filePath, readOpts)
+		var missing []string
+		groupMapping, missing = mapping.Resolve(groups)
+		if len(missing) > 0 && CreateMissingGroups(client, missing) {
+			if refreshed, err := client.GetAllGroups(); err == nil {
+				groupMapping, missing = mapping.Resolve(refreshed)
 			}
-			fmt.Printf(" "+utils.ColorBrightGreen+"✓ Selected group IDs: %v\n"+utils.ColorReset, selectedGroupIDs)
-		} else {
-			fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " No group assignment selected (skipping)")
+		}
+		for _, ref := range missing {
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Group '%s' does not exist on the panel, skipping\n"+utils.ColorReset, ref)
 		}
 	} else if gErr != nil {
 		fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Could not fetch groups: %v\n"+utils.ColorReset, gErr)
 	}
 
-	// Assign groups to all users to import
-	for i := range dataToImport.Users {
-		dataToImport.Users[i].GroupIDs = selectedGroupIDs
-	}
 	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " [3/3] " + utils.ColorBrightGreen + "Importing users to panel..." + utils.ColorReset)
 	fmt.Println(" " + utils.ColorBrightBlue + "└─────────────────────────────────────────────────────────────────┘" + utils.ColorReset)
 	successCount := 0
@@ -300,24 +447,65 @@ func ImportPasarGuardUsersFromJSON(client *clients.PasarGuardClient) {
 		prefetchErr = nil
 	}
 
-	// 3. Loop through each user and create/update it on the panel
-	for idx, user := range dataToImport.Users {
+	// 3. Decode each user from the file and create/update it on the panel
+	processedCount := 0
+	var importedUsers []models.PasarGuardUser // the fields of each user as sent that the verification pass compares
+	startedAt := time.Now()
+	journal := newImportJournal(filePath, client.BaseURL, startedAt)
+	for idx := 0; ; idx++ {
+		user, err := records.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Error reading user #%d from file: %v", idx+1, err))
+			failureCount++
+			break
+		}
+		processedCount++
+		// Assign groups by the inbound the user came from
+		user.GroupIDs = groupMapping.groupsFor(user)
 		fmt.Printf("\n " + utils.ColorBrightBlue + "════════════════════════════════════════════════════════════════════════\n" + utils.ColorReset)
 
 		if utils.VerboseMode {
 			fmt.Printf(utils.ColorBrightMagenta+"[DEBUG] Original - TotalGB: %d, RemainingTraffic: %d\n"+utils.ColorReset, user.TotalGB, user.RemainingTraffic)
 		}
 
-		if user.RemainingTraffic > 0 {
-			user.TotalGB = user.RemainingTraffic
-			if utils.VerboseMode {
-				fmt.Printf(utils.ColorBrightMagenta+"[DEBUG] Updated - TotalGB set to RemainingTraffic: %d\n"+utils.ColorReset, user.TotalGB)
+		carried := trafficPolicy.Apply(user.TotalGB, user.UsedTraffic, user.Enable)
+		user.TotalGB, user.UsedTraffic, user.Enable = carried.TotalGB, carried.UsedTraffic, carried.Enable
+		user.RemainingTraffic = 0
+		if user.TotalGB > 0 && user.TotalGB > user.UsedTraffic {
+			user.RemainingTraffic = user.TotalGB - user.UsedTraffic
+		}
+		if utils.VerboseMode {
+			fmt.Printf(utils.ColorBrightMagenta+"[DEBUG] Updated - TotalGB: %d, UsedTraffic: %d, Enable: %v\n"+utils.ColorReset, user.TotalGB, user.UsedTraffic, user.Enable)
+		}
+
+		rawExpiry := user.ExpiryTime
+		if rawExpiry == 0 && user.OnHoldExpireDuration > 0 {
+			rawExpiry = -user.OnHoldExpireDuration * 1000
+		}
+		now := time.Now()
+		expiry := expiryRules.Apply(rawExpiry, now)
+		user.ExpiryTime, user.OnHoldExpireDuration = expiry.Expire, 0
+		onHoldTimeout := user.OnHoldTimeout
+		user.OnHoldTimeout = 0
+		if expiry.DelayedStart > 0 {
+			if expiryRules.DelayedStartOnHold {
+				user.OnHoldExpireDuration = expiry.DelayedStart
+				if expiryRules.OnHoldTimeoutDays > 0 {
+					user.OnHoldTimeout = now.Unix() + int64(expiryRules.OnHoldTimeoutDays)*secondsPerDay
+				} else if onHoldTimeout > now.Unix() {
+					user.OnHoldTimeout = onHoldTimeout
+				}
+			} else {
+				user.ExpiryTime = now.Unix() + expiry.DelayedStart
 			}
 		}
-		user.UsedTraffic = 0
 
-		if user.ExpiryTime > 1e11 {
-			user.ExpiryTime = user.ExpiryTime / 1000
+		// The 3X-UI reset period is mapped at import time so -reset-map overrides apply to older files too.
+		if user.ResetDays > 0 {
+			user.DataLimitResetStrategy = models.ResetStrategyFromDays(user.ResetDays)
 		}
 
 		originalUsername := user.Username
@@ -333,13 +521,13 @@ func ImportPasarGuardUsersFromJSON(client *clients.PasarGuardClient) {
 		if email == "" {
 			email = fmt.Sprintf("User_%d", idx+1)
 		}
-		fmt.Printf(" "+utils.ColorBrightYellow+"[%d/%d] Processing: %s (Original: %s)\n"+utils.ColorReset, idx+1, len(dataToImport.Users), sanitizedUsername, originalUsername)
+		fmt.Printf(" "+utils.ColorBrightYellow+"[%d/%d] Processing: %s (Original: %s)\n"+utils.ColorReset, idx+1, header.TotalUsers, sanitizedUsername, originalUsername)
 		if utils.VerboseMode {
 			fmt.Printf(" "+utils.ColorCyan+"Protocol: %s | Port: %d\n"+utils.ColorReset, user.Protocol, user.Port)
 		}
 		quotaInGB := float64(user.TotalGB) / (1024 * 1024 * 1024)
 		if utils.VerboseMode {
-			fmt.Printf(" "+utils.ColorBrightGreen+"📊 Final Quota: %.2f GB (%d bytes) | Used Traffic reset to: 0\n"+utils.ColorReset, quotaInGB, user.TotalGB)
+			fmt.Printf(" "+utils.ColorBrightGreen+"📊 Final Quota: %.2f GB (%d bytes) | Used Traffic: %d bytes\n"+utils.ColorReset, quotaInGB, user.TotalGB, user.UsedTraffic)
 		}
 
 		newUUID := strings.ToLower(strings.TrimSpace(user.UUID))
@@ -400,11 +588,16 @@ func ImportPasarGuardUsersFromJSON(client *clients.PasarGuardClient) {
 				}
 
 				successCount++
+				importedUsers = append(importedUsers, verifiedFields(user))
+				journal.record(user.Username, newUUID, JournalUpdated)
 
 				updatedEntry := existingEntry
 				updatedEntry.Username = user.Username
 				updatedEntry.TotalGB = user.TotalGB
 				updatedEntry.ExpiryTime = user.ExpiryTime
+				updatedEntry.OnHoldExpireDuration = user.OnHoldExpireDuration
+				updatedEntry.OnHoldTimeout = user.OnHoldTimeout
+				updatedEntry.DataLimitResetStrategy = user.DataLimitResetStrategy
 				updatedEntry.Enable = user.Enable
 				updatedEntry.Note = user.Note
 				updatedEntry.LimitIP = user.LimitIP
@@ -458,6 +651,8 @@ func ImportPasarGuardUsersFromJSON(client *clients.PasarGuardClient) {
 
 				stored := user
 				stored.Username = candidateUsername
+				importedUsers = append(importedUsers, verifiedFields(stored))
+				journal.record(candidateUsername, newUUID, JournalCreated)
 				usersByUUID[newUUID] = stored
 				allUUIDsMap[newUUID] = stored
 				usersByUsername[usernameKey] = stored
@@ -493,15 +688,61 @@ func ImportPasarGuardUsersFromJSON(client *clients.PasarGuardClient) {
 	if failureCount > 0 {
 		fmt.Printf(" "+utils.ColorRed+"✗ Failed imports: %d\n"+utils.ColorReset, failureCount)
 	}
-	fmt.Printf(" "+utils.ColorCyan+"📊 Total users: %d\n\n"+utils.ColorReset, len(dataToImport.Users))
+	fmt.Printf(" "+utils.ColorCyan+"📊 Total users: %d\n\n"+utils.ColorReset, processedCount)
+	if len(journal.Entries) > 0 {
+		journalFile := JournalPath(filePath, startedAt)
+		if err := SaveImportJournal(journal, journalFile); err != nil {
+			utils.PrintWarning(fmt.Sprintf("Could not save import journal: %v", err))
+		} else {
+			fmt.Printf(" "+utils.ColorGreen+"📁 Import journal: "+utils.ColorReset+"%s\n\n", journalFile)
+		}
+	}
+
+	// 4. Re-fetch the imported users and check that quotas, expiries, status and groups landed
+	if len(importedUsers) > 0 {
+		report, err := VerifyPasarGuardImport(client, filePath, importedUsers)
+		if err != nil {
+			utils.PrintWarning(fmt.Sprintf("Verification skipped: %v", err))
+			return
+		}
+		PrintVerificationReport(report)
+		reportFile := filePath + ".verify.json"
+		if err := SaveVerificationReport(report, reportFile); err != nil {
+			utils.PrintWarning(fmt.Sprintf("Could not save verification report: %v", err))
+		} else {
+			fmt.Printf("\n "+utils.ColorGreen+"📁 Verification report: "+utils.ColorReset+"%s\n\n", reportFile)
+		}
+	}
 }
 
 // PromptForInputStyled displays a styled prompt and returns the user's input.
 func PromptForInputStyled(label, prefix, color string) string {
 	fmt.Printf("%s %s%s%s: ", prefix, color, label, utils.ColorReset)
-	var input string
-	fmt.Scanln(&input)
-	return strings.TrimSpace(input)
+	return utils.ReadLine()
+}
+
+// PromptForSecretStyled is PromptForInputStyled for passwords and keys: the input is not echoed.
+func PromptForSecretStyled(label, prefix, color string) string {
+	fmt.Printf("%s %s%s%s: ", prefix, color, label, utils.ColorReset)
+	return utils.ReadSecret()
+}
+
+// DecryptionOptions returns the key material needed to read filePath. Plain files need none;
+// for encrypted files the -identity flag is used when set, otherwise the user is asked for a key.
+func DecryptionOptions(filePath string) (storage.Options, error) {
+	encrypted, err := storage.IsEncrypted(filePath)
+	if err != nil || !encrypted {
+		return storage.Options{}, err
+	}
+	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightYellow + "🔒 File is encrypted" + utils.ColorReset)
+	if storage.IdentityFile != "" {
+		return storage.Options{Identities: []string{storage.IdentityFile}}, nil
+	}
+	key := PromptForSecretStyled("Enter passphrase, age secret key or identity file path", " │", utils.ColorBrightRed)
+	if key == "" {
+		return storage.Options{}, fmt.Errorf("no decryption key provided")
+	}
+	return storage.KeyOptions(key), nil
 }
 
 // extractAllUUIDsFromProxySettings extracts ALL UUID-like identifiers from all protocols
@@ -537,3 +778,35 @@ func extractAllUUIDsFromProxySettings(proxySettings map[string]interface{}) []st
 
 	return uuids
 }
+
+// verifyExportIntegrity checks filePath against its manifest before anything is sent to a panel.
+// Files without a manifest are accepted with a warning. It returns false when the import must stop.
+func verifyExportIntegrity(filePath string, opts storage.Options) bool {
+	manifest, err := storage.ReadManifest(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightYellow + "⚠️ No manifest found, skipping integrity check" + utils.ColorReset)
+		return true
+	}
+	var problems []string
+	if err == nil {
+		problems, err = storage.VerifyManifest(filePath, opts, manifest)
+	}
+	if err != nil {
+		problems = append(problems, err.Error())
+	}
+	if len(problems) == 0 {
+		fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+" "+utils.ColorGreen+"✓ Integrity verified (sha256 %s..., %d user(s))\n"+utils.ColorReset, manifest.SHA256[:12], manifest.TotalUsers)
+		return true
+	}
+
+	fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightRed + "❌ File does not match its manifest:" + utils.ColorReset)
+	for _, problem := range problems {
+		fmt.Printf(" "+utils.ColorBrightBlue+"│"+utils.ColorReset+"    - %s\n", problem)
+	}
+	if ForceImport {
+		fmt.Println(" " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightYellow + "⚠️ Continuing anyway (-force)" + utils.ColorReset)
+		return true
+	}
+	answer := PromptForInputStyled("Type 'force' to import anyway, or press Enter to abort", " │", utils.ColorBrightRed)
+	return strings.EqualFold(answer, "force")
+}
@@ -0,0 +1,72 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// What an import did to a user.
+const (
+	JournalCreated = "created"
+	JournalUpdated = "updated"
+)
+
+// JournalEntry records one user written by an import, under the username it got on the panel.
+type JournalEntry struct {
+	Username string `json:"username"`
+	UUID     string `json:"uuid"`
+	Action   string `json:"action"`
+}
+
+// ImportJournal lists the users an import wrote, so a failed or test import can be undone.
+type ImportJournal struct {
+	ImportedAt string         `json:"imported_at"`
+	SourceFile string         `json:"source_file"`
+	PanelURL   string         `json:"panel_url"`
+	Entries    []JournalEntry `json:"entries"`
+}
+
+// JournalPath returns where the journal of an import started at the given time is written. Every
+// run gets its own file, so re-importing the same file keeps the journals of earlier runs.
+func JournalPath(importFile string, startedAt time.Time) string {
+	return importFile + ".journal-" + startedAt.Format("20060102-150405") + ".json"
+}
+
+// newImportJournal starts the journal of an import.
+func newImportJournal(sourceFile, panelURL string, startedAt time.Time) *ImportJournal {
+	return &ImportJournal{ImportedAt: startedAt.Format(time.RFC3339), SourceFile: sourceFile, PanelURL: panelURL}
+}
+
+func (j *ImportJournal) record(username, uuid, action string) {
+	j.Entries = append(j.Entries, JournalEntry{Username: username, UUID: uuid, Action: action})
+}
+
+// SaveImportJournal writes the journal to a JSON file.
+func SaveImportJournal(journal *ImportJournal, filename string) error {
+	data, err := json.MarshalIndent(journal, "", " ")
+	if err != nil {
+		return fmt.Errorf("error creating journal JSON: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("error saving journal: %v", err)
+	}
+	return nil
+}
+
+// LoadImportJournal reads a journal written by SaveImportJournal.
+func LoadImportJournal(filename string) (*ImportJournal, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error reading journal '%s': %v", filename, err)
+	}
+	var journal ImportJournal
+	if err := json.Unmarshal(data, &journal); err != nil {
+		return nil, fmt.Errorf("error parsing journal '%s': %v", filename, err)
+	}
+	if journal.ImportedAt == "" && journal.Entries == nil {
+		return nil, fmt.Errorf("'%s' is not an import journal", filename)
+	}
+	return &journal, nil
+}
@@ -0,0 +1,197 @@
+package importers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// Port remapping modes.
+const (
+	RemapNone   = ""
+	RemapOffset = "offset" // every port is shifted by Offset
+	RemapRange  = "range"  // inbounds get the free ports of RangeStart..RangeEnd in file order
+	RemapManual = "manual" // the port of each inbound is asked for
+)
+
+// PortRemap rewrites inbound ports and listen addresses so a restore does not collide with
+// services already running on the target server.
+type PortRemap struct {
+	Mode       string
+	Offset     int
+	RangeStart int
+	RangeEnd   int
+	Listen     string // replaces every listen address when set; "-" clears it (all interfaces)
+	next       int
+}
+
+// Apply moves an inbound according to the remap. used holds the ports taken on the target.
+func (r *PortRemap) Apply(inbound *models.InboundData, used map[int]int) {
+	port, listen := inbound.Port, inbound.Listen
+	switch r.Mode {
+	case RemapOffset:
+		port += r.Offset
+	case RemapRange:
+		if r.next < r.RangeStart {
+			r.next = r.RangeStart
+		}
+		for ; r.next <= r.RangeEnd; r.next++ {
+			if _, taken := used[r.next]; !taken {
+				break
+			}
+		}
+		if r.next > r.RangeEnd {
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Port range %d-%d exhausted, '%s' keeps port %d\n"+utils.ColorReset, r.RangeStart, r.RangeEnd, inbound.Remark, inbound.Port)
+		} else {
+			port = r.next
+			r.next++
+		}
+	case RemapManual:
+		port, listen = promptInboundAddress(*inbound)
+	}
+	switch r.Listen {
+	case "":
+	case "-":
+		listen = ""
+	default:
+		listen = r.Listen
+	}
+	if port < 1 || port > 65535 {
+		fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Port %d is out of range, '%s' keeps port %d\n"+utils.ColorReset, port, inbound.Remark, inbound.Port)
+		port = inbound.Port
+	}
+	moveInbound(inbound, port, listen)
+}
+
+// firstUnprivilegedPort is where the search for a free port wraps around to. Lower ports are
+// usually taken by system services the panel does not know about, such as SSH.
+const firstUnprivilegedPort = 1024
+
+// moveToFreePort moves an inbound to the first port above its own that is free on the target,
+// wrapping around to the ports below it, and makes its tag unique if it still collides.
+func moveToFreePort(inbound *models.InboundData, usedPorts map[int]int, usedTags map[string]int) error {
+	port := 0
+	for p := inbound.Port + 1; p <= 65535 && port == 0; p++ {
+		if _, taken := usedPorts[p]; !taken {
+			port = p
+		}
+	}
+	for p := firstUnprivilegedPort; p < inbound.Port && port == 0; p++ {
+		if _, taken := usedPorts[p]; !taken {
+			port = p
+		}
+	}
+	if port == 0 {
+		return fmt.Errorf("no free port left for '%s'", inbound.Remark)
+	}
+	moveInbound(inbound, port, inbound.Listen)
+	if _, taken := usedTags[inbound.Tag]; taken {
+		inbound.Tag = fmt.Sprintf("%s-%d", inbound.Tag, port)
+	}
+	return nil
+}
+
+// moveInbound sets a new port and listen address and rewrites the tag when it embeds them:
+// 3X-UI's generated "inbound-<port>" and "inbound-<listen>:<port>" tags are regenerated, and
+// custom tags ending in the old port get the new one.
+func moveInbound(inbound *models.InboundData, port int, listen string) {
+	if port == inbound.Port && listen == inbound.Listen {
+		return
+	}
+	oldPort := strconv.Itoa(inbound.Port)
+	switch {
+	case inbound.Tag == generatedTag(inbound.Listen, inbound.Port):
+		inbound.Tag = generatedTag(listen, port)
+	case strings.HasSuffix(inbound.Tag, oldPort) && !endsWithDigitBefore(inbound.Tag, len(oldPort)):
+		inbound.Tag = strings.TrimSuffix(inbound.Tag, oldPort) + strconv.Itoa(port)
+	}
+	fmt.Printf(" "+utils.ColorCyan+"↪ Remapped to %s (tag %s)\n"+utils.ColorReset, formatAddress(listen, port), inbound.Tag)
+	inbound.Port, inbound.Listen = port, listen
+}
+
+// generatedTag returns the tag 3X-UI generates for an inbound.
+func generatedTag(listen string, port int) string {
+	if listen == "" || listen == "0.0.0.0" || listen == "::" {
+		return fmt.Sprintf("inbound-%d", port)
+	}
+	return fmt.Sprintf("inbound-%s:%d", listen, port)
+}
+
+// endsWithDigitBefore reports whether the character before the last n characters of s is a
+// digit, i.e. the suffix is only the tail of a longer number.
+func endsWithDigitBefore(s string, n int) bool {
+	i := len(s) - n - 1
+	return i >= 0 && s[i] >= '0' && s[i] <= '9'
+}
+
+// formatAddress renders listen:port, with * for all interfaces.
+func formatAddress(listen string, port int) string {
+	if listen == "" {
+		return fmt.Sprintf("*:%d", port)
+	}
+	return fmt.Sprintf("%s:%d", listen, port)
+}
+
+// promptInboundAddress asks for the new port and listen address of one inbound.
+func promptInboundAddress(inbound models.InboundData) (int, string) {
+	port := inbound.Port
+	for {
+		input := PromptForInputStyled(fmt.Sprintf("New port for '%s' [%d]", inbound.Remark, inbound.Port), " ➜", utils.ColorBrightYellow)
+		if input == "" {
+			break
+		}
+		n, err := strconv.Atoi(input)
+		if err == nil && n >= 1 && n <= 65535 {
+			port = n
+			break
+		}
+		fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Invalid port '%s'\n"+utils.ColorReset, input)
+	}
+	listen := inbound.Listen
+	input := PromptForInputStyled(fmt.Sprintf("Listen address for '%s' [%s] ('-' for all interfaces)", inbound.Remark, formatAddress(inbound.Listen, port)), " ➜", utils.ColorBrightYellow)
+	if input == "-" {
+		listen = ""
+	} else if input != "" {
+		listen = input
+	}
+	return port, listen
+}
+
+// PromptPortRemap asks how inbound ports and listen addresses are rewritten on import.
+func PromptPortRemap() PortRemap {
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Port remapping:" + utils.ColorReset)
+	fmt.Println(" \t[1] Keep ports (default)")
+	fmt.Println(" \t[2] Shift all ports by an offset")
+	fmt.Println(" \t[3] Assign free ports from a range")
+	fmt.Println(" \t[4] Choose port and listen address per inbound")
+	var remap PortRemap
+	switch PromptForInputStyled("Select [1]", " ➜", utils.ColorBrightYellow) {
+	case "2":
+		remap.Mode = RemapOffset
+		for remap.Offset == 0 {
+			remap.Offset = promptInt("Port offset (e.g. 10000 or -100)", true)
+		}
+	case "3":
+		remap.Mode = RemapRange
+		for {
+			input := PromptForInputStyled("Port range (e.g. 20000-20100)", " ➜", utils.ColorBrightYellow)
+			from, to, found := strings.Cut(input, "-")
+			start, err1 := strconv.Atoi(strings.TrimSpace(from))
+			end, err2 := strconv.Atoi(strings.TrimSpace(to))
+			if found && err1 == nil && err2 == nil && start >= 1 && start <= end && end <= 65535 {
+				remap.RangeStart, remap.RangeEnd = start, end
+				break
+			}
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Invalid range '%s'\n"+utils.ColorReset, input)
+		}
+	case "4":
+		remap.Mode = RemapManual
+	}
+	if remap.Mode != RemapManual {
+		remap.Listen = PromptForInputStyled("New listen address for all inbounds (Enter to keep, '-' for all interfaces)", " ➜", utils.ColorBrightYellow)
+	}
+	return remap
+}
@@ -0,0 +1,90 @@
+package importers
+
+import (
+	"testing"
+
+	"panels_user_manager/pkg/models"
+)
+
+func TestPortRemapApply(t *testing.T) {
+	used := map[int]int{20001: 1, 20002: 2}
+	tests := []struct {
+		name       string
+		remap      PortRemap
+		inbound    models.InboundData
+		wantPort   int
+		wantListen string
+		wantTag    string
+	}{
+		{"offset", PortRemap{Mode: RemapOffset, Offset: 10000}, models.InboundData{Port: 443, Tag: "inbound-443"}, 10443, "", "inbound-10443"},
+		{"offset out of range keeps port", PortRemap{Mode: RemapOffset, Offset: 65000}, models.InboundData{Port: 8443, Tag: "inbound-8443"}, 8443, "", "inbound-8443"},
+		{"range skips used ports", PortRemap{Mode: RemapRange, RangeStart: 20001, RangeEnd: 20010}, models.InboundData{Port: 443, Tag: "inbound-443"}, 20003, "", "inbound-20003"},
+		{"range exhausted keeps port", PortRemap{Mode: RemapRange, RangeStart: 20001, RangeEnd: 20002}, models.InboundData{Port: 443, Tag: "inbound-443"}, 443, "", "inbound-443"},
+		{"listen only", PortRemap{Listen: "10.0.0.1"}, models.InboundData{Port: 443, Tag: "inbound-443"}, 443, "10.0.0.1", "inbound-10.0.0.1:443"},
+		{"listen cleared", PortRemap{Listen: "-"}, models.InboundData{Port: 443, Listen: "10.0.0.1", Tag: "inbound-10.0.0.1:443"}, 443, "", "inbound-443"},
+		{"custom tag ending in the port", PortRemap{Mode: RemapOffset, Offset: 1}, models.InboundData{Port: 443, Tag: "vless-443"}, 444, "", "vless-444"},
+		{"custom tag ending in a longer number", PortRemap{Mode: RemapOffset, Offset: 1}, models.InboundData{Port: 443, Tag: "vless-8443"}, 444, "", "vless-8443"},
+		{"custom tag", PortRemap{Mode: RemapOffset, Offset: 1}, models.InboundData{Port: 443, Tag: "reality"}, 444, "", "reality"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inbound := tt.inbound
+			tt.remap.Apply(&inbound, used)
+			if inbound.Port != tt.wantPort || inbound.Listen != tt.wantListen || inbound.Tag != tt.wantTag {
+				t.Errorf("got %s tag %s, want %s tag %s", formatAddress(inbound.Listen, inbound.Port), inbound.Tag, formatAddress(tt.wantListen, tt.wantPort), tt.wantTag)
+			}
+		})
+	}
+}
+
+func TestPortRemapRangeOrder(t *testing.T) {
+	remap := PortRemap{Mode: RemapRange, RangeStart: 30000, RangeEnd: 30010}
+	used := map[int]int{30001: 1}
+	var got []int
+	for _, port := range []int{443, 8443, 2053} {
+		inbound := models.InboundData{Port: port}
+		remap.Apply(&inbound, used)
+		got = append(got, inbound.Port)
+	}
+	if got[0] != 30000 || got[1] != 30002 || got[2] != 30003 {
+		t.Errorf("ports = %v, want [30000 30002 30003]", got)
+	}
+}
+
+func TestMoveToFreePort(t *testing.T) {
+	tests := []struct {
+		name      string
+		inbound   models.InboundData
+		usedPorts map[int]int
+		usedTags  map[string]int
+		wantPort  int
+		wantTag   string
+		wantErr   bool
+	}{
+		{"next port up", models.InboundData{Port: 443, Tag: "inbound-443"}, map[int]int{443: 1, 444: 2}, map[string]int{"inbound-443": 1}, 445, "inbound-445", false},
+		{"colliding custom tag gets the port", models.InboundData{Port: 443, Tag: "reality"}, map[int]int{443: 1}, map[string]int{"reality": 1}, 444, "reality-444", false},
+		{"free custom tag is kept", models.InboundData{Port: 443, Tag: "reality"}, map[int]int{443: 1}, map[string]int{}, 444, "reality", false},
+		{"wraps to unprivileged ports", models.InboundData{Port: 65535, Tag: "inbound-65535"}, map[int]int{65535: 1, 1024: 2}, map[string]int{}, 1025, "inbound-1025", false},
+		{"nothing free", models.InboundData{Port: 1025, Tag: "x"}, allPortsFrom(1024), map[string]int{}, 1025, "x", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inbound := tt.inbound
+			err := moveToFreePort(&inbound, tt.usedPorts, tt.usedTags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if inbound.Port != tt.wantPort || inbound.Tag != tt.wantTag {
+				t.Errorf("got port %d tag %s, want port %d tag %s", inbound.Port, inbound.Tag, tt.wantPort, tt.wantTag)
+			}
+		})
+	}
+}
+
+func allPortsFrom(first int) map[int]int {
+	used := make(map[int]int)
+	for p := first; p <= 65535; p++ {
+		used[p] = p
+	}
+	return used
+}
@@ -0,0 +1,130 @@
+package importers
+
+import (
+	"fmt"
+	"strconv"
+
+	"panels_user_manager/pkg/utils"
+)
+
+// Traffic carryover modes.
+const (
+	TrafficKeepTotal        = "keep-total"         // original quota and used traffic are both kept
+	TrafficRemainingAsTotal = "remaining-as-total" // remaining traffic becomes the new quota, usage starts at 0
+	TrafficResetUsage       = "reset-usage"        // original quota, usage starts at 0
+	TrafficBonus            = "bonus"              // remaining traffic plus a percentage of the original quota, usage starts at 0
+)
+
+// How users that have used up their quota are treated.
+const (
+	ExhaustedKeep    = "keep"    // stay exhausted with a minimal quota
+	ExhaustedDisable = "disable" // are disabled
+)
+
+// exhaustedQuota is the smallest finite quota. Both panels treat a quota of 0 as unlimited,
+// so exhausted users get this instead.
+const exhaustedQuota = 1
+
+// TrafficPolicy decides how quota and usage from an export file are carried into the target panel.
+type TrafficPolicy struct {
+	Mode         string
+	BonusPercent float64
+	Exhausted    string
+}
+
+// TrafficResult is the quota, usage and status a user is imported with.
+type TrafficResult struct {
+	TotalGB     int64
+	UsedTraffic int64
+	Enable      bool
+}
+
+// Apply computes the imported traffic values from the exported quota and usage.
+// A quota of 0 or less means unlimited and stays unlimited.
+func (p TrafficPolicy) Apply(total, used int64, enabled bool) TrafficResult {
+	if used < 0 {
+		used = 0
+	}
+	if total <= 0 {
+		if p.Mode == TrafficKeepTotal {
+			return TrafficResult{TotalGB: 0, UsedTraffic: used, Enable: enabled}
+		}
+		return TrafficResult{TotalGB: 0, UsedTraffic: 0, Enable: enabled}
+	}
+
+	remaining := total - used
+	if remaining <= 0 {
+		// Exhausted users stay exhausted under every policy; they never get fresh or unlimited traffic.
+		result := TrafficResult{TotalGB: exhaustedQuota, Enable: enabled}
+		if p.Mode == TrafficKeepTotal {
+			result.TotalGB, result.UsedTraffic = total, used
+		}
+		if p.Exhausted == ExhaustedDisable {
+			result.Enable = false
+		}
+		return result
+	}
+
+	result := TrafficResult{Enable: enabled}
+	switch p.Mode {
+	case TrafficKeepTotal:
+		result.TotalGB, result.UsedTraffic = total, used
+	case TrafficResetUsage:
+		result.TotalGB = total
+	case TrafficBonus:
+		result.TotalGB = remaining + int64(float64(total)*p.BonusPercent/100)
+	default:
+		result.TotalGB = remaining
+	}
+	return result
+}
+
+// String describes the policy for the import summary.
+func (p TrafficPolicy) String() string {
+	desc := map[string]string{
+		TrafficKeepTotal:        "keep total and used traffic",
+		TrafficRemainingAsTotal: "remaining traffic as new total",
+		TrafficResetUsage:       "keep total, reset usage",
+		TrafficBonus:            fmt.Sprintf("remaining traffic + %.0f%% bonus", p.BonusPercent),
+	}[p.Mode]
+	if p.Exhausted == ExhaustedDisable {
+		return desc + ", disable exhausted users"
+	}
+	return desc + ", exhausted users stay exhausted"
+}
+
+// PromptTrafficPolicy asks how quota and usage should be carried over. usageNote is shown next to
+// the options that keep used traffic, for panels that cannot restore it.
+func PromptTrafficPolicy(usageNote string) TrafficPolicy {
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Traffic carryover policy:" + utils.ColorReset)
+	fmt.Println(" \t[1] Keep total quota and used traffic" + usageNote)
+	fmt.Println(" \t[2] Remaining traffic becomes the new total (default)")
+	fmt.Println(" \t[3] Keep total quota, reset used traffic")
+	fmt.Println(" \t[4] Remaining traffic plus a bonus percentage of the total")
+	policy := TrafficPolicy{Mode: TrafficRemainingAsTotal, Exhausted: ExhaustedKeep}
+	switch PromptForInputStyled("Select policy [2]", " ➜", utils.ColorBrightYellow) {
+	case "1":
+		policy.Mode = TrafficKeepTotal
+	case "3":
+		policy.Mode = TrafficResetUsage
+	case "4":
+		policy.Mode = TrafficBonus
+		for {
+			input := PromptForInputStyled("Bonus percentage of the total quota", " ➜", utils.ColorBrightYellow)
+			bonus, err := strconv.ParseFloat(input, 64)
+			if err == nil && bonus >= 0 {
+				policy.BonusPercent = bonus
+				break
+			}
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Invalid percentage '%s'\n"+utils.ColorReset, input)
+		}
+	}
+
+	fmt.Println(" \t[1] Users with no traffic left stay exhausted (default)")
+	fmt.Println(" \t[2] Users with no traffic left are disabled")
+	if PromptForInputStyled("Select [1]", " ➜", utils.ColorBrightYellow) == "2" {
+		policy.Exhausted = ExhaustedDisable
+	}
+	fmt.Printf(" "+utils.ColorBrightGreen+"✓ Traffic policy: %s\n"+utils.ColorReset, policy)
+	return policy
+}
@@ -0,0 +1,40 @@
+package importers
+
+import "testing"
+
+func TestTrafficPolicyApply(t *testing.T) {
+	const gb = 1 << 30
+	tests := []struct {
+		name    string
+		policy  TrafficPolicy
+		total   int64
+		used    int64
+		enabled bool
+		want    TrafficResult
+	}{
+		{"keep total", TrafficPolicy{Mode: TrafficKeepTotal}, 10 * gb, 4 * gb, true, TrafficResult{TotalGB: 10 * gb, UsedTraffic: 4 * gb, Enable: true}},
+		{"remaining as total", TrafficPolicy{Mode: TrafficRemainingAsTotal}, 10 * gb, 4 * gb, true, TrafficResult{TotalGB: 6 * gb, Enable: true}},
+		{"reset usage", TrafficPolicy{Mode: TrafficResetUsage}, 10 * gb, 4 * gb, true, TrafficResult{TotalGB: 10 * gb, Enable: true}},
+		{"bonus", TrafficPolicy{Mode: TrafficBonus, BonusPercent: 10}, 10 * gb, 4 * gb, true, TrafficResult{TotalGB: 7 * gb, Enable: true}},
+		{"disabled user stays disabled", TrafficPolicy{Mode: TrafficRemainingAsTotal}, 10 * gb, 4 * gb, false, TrafficResult{TotalGB: 6 * gb}},
+		{"negative usage counts as none", TrafficPolicy{Mode: TrafficRemainingAsTotal}, 10 * gb, -1, true, TrafficResult{TotalGB: 10 * gb, Enable: true}},
+
+		{"unlimited keeps usage under keep total", TrafficPolicy{Mode: TrafficKeepTotal}, 0, 4 * gb, true, TrafficResult{UsedTraffic: 4 * gb, Enable: true}},
+		{"unlimited stays unlimited", TrafficPolicy{Mode: TrafficBonus, BonusPercent: 50}, 0, 4 * gb, true, TrafficResult{Enable: true}},
+		{"negative quota is unlimited", TrafficPolicy{Mode: TrafficResetUsage}, -1, 4 * gb, true, TrafficResult{Enable: true}},
+
+		{"exhausted keeps quota and usage under keep total", TrafficPolicy{Mode: TrafficKeepTotal, Exhausted: ExhaustedKeep}, 10 * gb, 10 * gb, true, TrafficResult{TotalGB: 10 * gb, UsedTraffic: 10 * gb, Enable: true}},
+		{"exhausted gets no fresh quota on reset", TrafficPolicy{Mode: TrafficResetUsage, Exhausted: ExhaustedKeep}, 10 * gb, 10 * gb, true, TrafficResult{TotalGB: exhaustedQuota, Enable: true}},
+		{"exhausted gets no bonus", TrafficPolicy{Mode: TrafficBonus, BonusPercent: 50, Exhausted: ExhaustedKeep}, 10 * gb, 12 * gb, true, TrafficResult{TotalGB: exhaustedQuota, Enable: true}},
+		{"exhausted is never unlimited", TrafficPolicy{Mode: TrafficRemainingAsTotal, Exhausted: ExhaustedKeep}, 10 * gb, 12 * gb, true, TrafficResult{TotalGB: exhaustedQuota, Enable: true}},
+		{"exhausted disabled", TrafficPolicy{Mode: TrafficRemainingAsTotal, Exhausted: ExhaustedDisable}, 10 * gb, 10 * gb, true, TrafficResult{TotalGB: exhaustedQuota}},
+		{"exhausted disabled under keep total", TrafficPolicy{Mode: TrafficKeepTotal, Exhausted: ExhaustedDisable}, 10 * gb, 11 * gb, true, TrafficResult{TotalGB: 10 * gb, UsedTraffic: 11 * gb}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Apply(tt.total, tt.used, tt.enabled); got != tt.want {
+				t.Errorf("Apply(%d, %d, %v) = %+v, want %+v", tt.total, tt.used, tt.enabled, got, tt.want)
+			}
+		})
+	}
+}
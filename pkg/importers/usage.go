@@ -0,0 +1,118 @@
+package importers
+
+import (
+	"fmt"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// usageRestore collects the traffic counters of imported 3X-UI clients, which the inbound API
+// always creates at 0, and sets them once the inbounds are in place.
+type usageRestore struct {
+	traffic map[string]models.ClientTraffic // by client email
+	refs    map[string][]usageRef           // where each email was imported, by client email
+}
+
+// usageRef is an imported client and the ID of the inbound it was imported into on the target.
+type usageRef struct {
+	inboundID int
+	protocol  string
+	client    models.ClientDetails
+}
+
+// add queues the usage of clients imported into an inbound. Exports made before up and down
+// were stored separately only have the total, which is restored as download.
+func (u *usageRestore) add(inboundID int, protocol string, imported []models.ClientDetails) {
+	if u.traffic == nil {
+		u.traffic = make(map[string]models.ClientTraffic)
+		u.refs = make(map[string][]usageRef)
+	}
+	for _, c := range imported {
+		if c.ClientEmail == "" || c.TrafficUsed <= 0 {
+			continue
+		}
+		t := models.ClientTraffic{Up: c.TrafficUp, Down: c.TrafficDown}
+		if t.Up+t.Down == 0 {
+			t.Down = c.TrafficUsed
+		}
+		u.traffic[c.ClientEmail] = t
+		u.refs[c.ClientEmail] = append(u.refs[c.ClientEmail], usageRef{inboundID: inboundID, protocol: protocol, client: c})
+	}
+}
+
+// restore sets the queued counters through the API. Counters the panel refuses are offered
+// to be written into its database file instead. Exhausted clients whose usage could not be
+// restored either way get the minimal quota, so they stay exhausted.
+func (u *usageRestore) restore(client *clients.ThreeXUIClient) {
+	if len(u.traffic) == 0 {
+		return
+	}
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "Restoring traffic counters:" + utils.ColorReset)
+	failed := make(map[string]models.ClientTraffic)
+	var lastErr error
+	for email, t := range u.traffic {
+		if err := client.SetClientTraffic(email, t.Up, t.Down); err != nil {
+			utils.VerboseLog("Could not set traffic of %s: %v", email, err)
+			failed[email], lastErr = t, err
+		}
+	}
+	fmt.Printf(" "+utils.ColorGreen+"✓ Restored usage of %d client(s)\n"+utils.ColorReset, len(u.traffic)-len(failed))
+	if len(failed) == 0 {
+		return
+	}
+
+	fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ The panel did not accept usage for %d client(s): %v\n"+utils.ColorReset, len(failed), lastErr)
+	fmt.Println(" " + utils.ColorBrightYellow + "  Stop x-ui on the target server before writing its database directly." + utils.ColorReset)
+	dbPath := PromptForInputStyled("Path to x-ui.db to restore them offline (Enter to skip)", " ➜", utils.ColorBrightYellow)
+	if dbPath != "" {
+		restored, err := clients.RestoreTrafficInDB(dbPath, failed)
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Offline restore failed: %v", err))
+		} else {
+			fmt.Printf(" "+utils.ColorGreen+"✓ Restored usage of %d client(s) in %s\n"+utils.ColorReset, len(restored), dbPath)
+			for _, email := range restored {
+				delete(failed, email)
+			}
+			if len(failed) > 0 {
+				fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ %d client(s) were not found in client_traffics\n"+utils.ColorReset, len(failed))
+			}
+		}
+	}
+	if len(failed) > 0 {
+		u.keepExhausted(client, failed)
+	}
+}
+
+// keepExhausted lowers the quota of exhausted clients whose usage was not restored to
+// exhaustedQuota, since with 0 usage they would get their full quota back.
+func (u *usageRestore) keepExhausted(client *clients.ThreeXUIClient, unrestored map[string]models.ClientTraffic) {
+	lowered, fresh := 0, 0
+	for email := range unrestored {
+		exhausted := false
+		for _, ref := range u.refs[email] {
+			c := ref.client
+			if c.ClientTotalGB <= 0 || c.TrafficUsed < c.ClientTotalGB {
+				continue
+			}
+			exhausted = true
+			key := clients.ClientKey(ref.protocol, clients.ClientMap(c))
+			c.ClientTotalGB = exhaustedQuota
+			if err := client.UpdateClient(ref.inboundID, key, clients.ClientMap(c)); err != nil {
+				fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ %s is exhausted but keeps its full quota: %v\n"+utils.ColorReset, email, err)
+				continue
+			}
+			lowered++
+		}
+		if !exhausted {
+			fresh++
+		}
+	}
+	if lowered > 0 {
+		fmt.Printf(" "+utils.ColorGreen+"✓ %d exhausted client(s) kept exhausted with a minimal quota\n"+utils.ColorReset, lowered)
+	}
+	if fresh > 0 {
+		fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ %d client(s) start with 0 usage and their full quota\n"+utils.ColorReset, fresh)
+	}
+}
@@ -0,0 +1,201 @@
+package importers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// Discrepancy is a single field of an imported user that did not land on the panel as sent.
+type Discrepancy struct {
+	Username string `json:"username"`
+	UUID     string `json:"uuid"`
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// VerificationReport is the result of comparing imported users against the live panel.
+type VerificationReport struct {
+	VerifiedAt    string        `json:"verified_at"`
+	SourceFile    string        `json:"source_file"`
+	CheckedUsers  int           `json:"checked_users"`
+	MatchedUsers  int           `json:"matched_users"`
+	MissingUsers  int           `json:"missing_users"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+}
+
+// VerifyPasarGuardImport re-fetches all users from the panel and compares the UUID, data limit,
+// expiry, status and groups of every imported user with the values that were sent.
+func VerifyPasarGuardImport(client *clients.PasarGuardClient, sourceFile string, imported []models.PasarGuardUser) (*VerificationReport, error) {
+	liveUsers, err := client.GetAllUsers()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching users for verification: %v", err)
+	}
+
+	byUUID := make(map[string]models.PasarGuardUser)
+	byUsername := make(map[string]models.PasarGuardUser)
+	for _, live := range liveUsers {
+		if key := strings.ToLower(strings.TrimSpace(live.UUID)); key != "" {
+			byUUID[key] = live
+		}
+		for _, uuid := range extractAllUUIDsFromProxySettings(live.ProxySettings) {
+			byUUID[uuid] = live
+		}
+		byUsername[strings.ToLower(strings.TrimSpace(live.Username))] = live
+	}
+
+	report := &VerificationReport{
+		VerifiedAt:    time.Now().Format(time.RFC3339),
+		SourceFile:    sourceFile,
+		Discrepancies: []Discrepancy{},
+	}
+	for _, want := range imported {
+		report.CheckedUsers++
+		uuidKey := strings.ToLower(strings.TrimSpace(want.UUID))
+		got, found := byUUID[uuidKey]
+		if !found {
+			got, found = byUsername[strings.ToLower(strings.TrimSpace(want.Username))]
+		}
+		if !found {
+			report.MissingUsers++
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Username: want.Username, UUID: want.UUID, Field: "user", Expected: "present", Actual: "missing",
+			})
+			continue
+		}
+
+		before := len(report.Discrepancies)
+		add := func(field, expected, actual string) {
+			report.Discrepancies = append(report.Discrepancies, Discrepancy{
+				Username: want.Username, UUID: want.UUID, Field: field, Expected: expected, Actual: actual,
+			})
+		}
+		if _, ok := byUUID[uuidKey]; !ok {
+			add("uuid", want.UUID, got.UUID)
+		}
+		if normalizeLimit(want.TotalGB) != normalizeLimit(got.TotalGB) {
+			add("data_limit", formatLimit(want.TotalGB), formatLimit(got.TotalGB))
+		}
+		if normalizeExpiry(want.ExpiryTime) != normalizeExpiry(got.ExpiryTime) {
+			add("expire", formatExpiry(want.ExpiryTime), formatExpiry(got.ExpiryTime))
+		}
+		if want.OnHoldExpireDuration != got.OnHoldExpireDuration {
+			add("on_hold_expire_duration", fmt.Sprint(want.OnHoldExpireDuration), fmt.Sprint(got.OnHoldExpireDuration))
+		}
+		if want.DataLimitResetStrategy != "" && want.DataLimitResetStrategy != got.DataLimitResetStrategy {
+			add("data_limit_reset_strategy", want.DataLimitResetStrategy, got.DataLimitResetStrategy)
+		}
+		if want.Enable != got.Enable {
+			add("status", formatStatus(want.Enable), formatStatus(got.Enable))
+		}
+		if formatGroups(want.GroupIDs) != formatGroups(got.GroupIDs) {
+			add("group_ids", formatGroups(want.GroupIDs), formatGroups(got.GroupIDs))
+		}
+		if len(report.Discrepancies) == before {
+			report.MatchedUsers++
+		}
+	}
+	return report, nil
+}
+
+// verifiedFields keeps only the fields VerifyPasarGuardImport compares, so a large import does
+// not hold every user's proxy settings and notes until the verification pass.
+func verifiedFields(user models.PasarGuardUser) models.PasarGuardUser {
+	return models.PasarGuardUser{
+		Username:               user.Username,
+		UUID:                   user.UUID,
+		TotalGB:                user.TotalGB,
+		ExpiryTime:             user.ExpiryTime,
+		OnHoldExpireDuration:   user.OnHoldExpireDuration,
+		DataLimitResetStrategy: user.DataLimitResetStrategy,
+		Enable:                 user.Enable,
+		GroupIDs:               user.GroupIDs,
+	}
+}
+
+// PrintVerificationReport prints a summary and every discrepancy found.
+func PrintVerificationReport(report *VerificationReport) {
+	fmt.Println("\n" + utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Println(utils.ColorBrightCyan + "║" + utils.ColorReset + utils.CenterText(utils.ColorBold+utils.ColorBrightYellow+"🔎 POST-IMPORT VERIFICATION"+utils.ColorReset, 70) + utils.ColorBrightCyan + "║" + utils.ColorReset)
+	fmt.Println(utils.ColorBrightCyan + strings.Repeat("═", 72) + utils.ColorReset)
+	fmt.Printf("\n "+utils.ColorCyan+"📊 Checked users: %d\n"+utils.ColorReset, report.CheckedUsers)
+	fmt.Printf(" "+utils.ColorGreen+"✓ Fully matching: %d\n"+utils.ColorReset, report.MatchedUsers)
+	if report.MissingUsers > 0 {
+		fmt.Printf(" "+utils.ColorRed+"✗ Missing on panel: %d\n"+utils.ColorReset, report.MissingUsers)
+	}
+	if len(report.Discrepancies) == 0 {
+		fmt.Println(" " + utils.ColorBrightGreen + "✅ All imported users match the panel" + utils.ColorReset)
+		return
+	}
+	fmt.Printf(" "+utils.ColorYellow+"⚠️ Discrepancies: %d\n\n"+utils.ColorReset, len(report.Discrepancies))
+	for _, d := range report.Discrepancies {
+		fmt.Printf(" "+utils.ColorBrightYellow+"%-25s"+utils.ColorReset+" %-10s expected %s, got %s\n", d.Username[:utils.Min(25, len(d.Username))], d.Field, d.Expected, d.Actual)
+	}
+}
+
+// SaveVerificationReport writes the report as JSON. It contains UUIDs, so it is saved with mode 0600.
+func SaveVerificationReport(report *VerificationReport, filename string) error {
+	data, err := json.MarshalIndent(report, "", " ")
+	if err != nil {
+		return fmt.Errorf("error creating report JSON: %v", err)
+	}
+	if err := os.WriteFile(filename, data, 0600); err != nil {
+		return fmt.Errorf("error saving report: %v", err)
+	}
+	return nil
+}
+
+// normalizeLimit treats every non-positive data limit as unlimited.
+func normalizeLimit(limit int64) int64 {
+	if limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// normalizeExpiry converts millisecond timestamps to seconds and treats non-positive values as "never".
+func normalizeExpiry(expiry int64) int64 {
+	if expiry > 1e11 {
+		expiry = expiry / 1000
+	}
+	if expiry < 0 {
+		return 0
+	}
+	return expiry
+}
+
+func formatLimit(limit int64) string {
+	if normalizeLimit(limit) == 0 {
+		return "unlimited"
+	}
+	return utils.FormatBytes(limit)
+}
+
+func formatExpiry(expiry int64) string {
+	expiry = normalizeExpiry(expiry)
+	if expiry == 0 {
+		return "never"
+	}
+	return time.Unix(expiry, 0).Format("2006-01-02 15:04:05")
+}
+
+func formatStatus(enabled bool) string {
+	if enabled {
+		return "active"
+	}
+	return "disabled"
+}
+
+func formatGroups(ids []int) string {
+	sorted := append([]int(nil), ids...)
+	sort.Ints(sorted)
+	return fmt.Sprint(sorted)
+}
@@ -0,0 +1,135 @@
+package importers
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// wireGuardPlan holds the choice made for wireguard inbounds, asked once per import.
+type wireGuardPlan struct {
+	asked    bool
+	rotate   bool
+	endpoint string // host peers connect to
+	dns      string
+	outDir   string // where regenerated client configs are written
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// prompt asks whether server keys are preserved or rotated. Rotating also asks where peers
+// connect to, so their configs can be regenerated.
+func (p *wireGuardPlan) prompt(baseURL string) {
+	p.asked = true
+	fmt.Println("\n " + utils.ColorBrightBlue + "│" + utils.ColorReset + " " + utils.ColorBrightCyan + "WireGuard server keys:" + utils.ColorReset)
+	fmt.Println(" \t[1] Preserve keys, existing peer configs keep working (default)")
+	fmt.Println(" \t[2] Rotate keys and write new peer configs (.conf + QR code)")
+	if PromptForInputStyled("Select [1]", " ➜", utils.ColorBrightYellow) != "2" {
+		return
+	}
+	p.rotate = true
+
+	host := ""
+	if u, err := url.Parse(baseURL); err == nil {
+		host = u.Hostname()
+	}
+	p.endpoint = host
+	if input := PromptForInputStyled(fmt.Sprintf("Server address in peer configs [%s]", host), " ➜", utils.ColorBrightYellow); input != "" {
+		p.endpoint = input
+	}
+	p.dns = "1.1.1.1, 1.0.0.1"
+	if input := PromptForInputStyled(fmt.Sprintf("DNS servers in peer configs [%s]", p.dns), " ➜", utils.ColorBrightYellow); input != "" {
+		p.dns = input
+	}
+	p.outDir = "wireguard_configs"
+	if input := PromptForInputStyled(fmt.Sprintf("Directory for peer configs [%s]", p.outDir), " ➜", utils.ColorBrightYellow); input != "" {
+		p.outDir = input
+	}
+}
+
+// rotateKeys gives the inbound a new server key pair and returns the new public key.
+func (p *wireGuardPlan) rotateKeys(inbound *models.InboundData) (string, error) {
+	settings, publicKey, err := clients.RotateWireGuardKey(inbound.OriginalSettings)
+	if err != nil {
+		return "", fmt.Errorf("could not rotate keys for '%s': %v", inbound.Remark, err)
+	}
+	inbound.OriginalSettings = settings
+	fmt.Printf(" "+utils.ColorCyan+"🔑 New server public key: %s\n"+utils.ColorReset, publicKey)
+	return publicKey, nil
+}
+
+// writePeerConfigs writes a client config and QR code for every peer of a rotated inbound.
+// Peers without a stored private key cannot get a complete config and are reported instead.
+func (p *wireGuardPlan) writePeerConfigs(inbound models.InboundData, serverPublicKey string) {
+	settings, err := clients.ParseWireGuardSettings(inbound.OriginalSettings)
+	if err != nil {
+		fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Could not read peers of '%s': %v\n"+utils.ColorReset, inbound.Remark, err)
+		return
+	}
+	if err := os.MkdirAll(p.outDir, 0700); err != nil {
+		fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Could not create %s: %v\n"+utils.ColorReset, p.outDir, err)
+		return
+	}
+	written := 0
+	for i, peer := range settings.Peers {
+		if peer.PrivateKey == "" {
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Peer %d of '%s' has no stored private key; re-issue it manually\n"+utils.ColorReset, i+1, inbound.Remark)
+			continue
+		}
+		conf := p.peerConfig(inbound, settings.MTU, peer, serverPublicKey)
+		// Remarks need not be unique, so the port keeps the files of different inbounds apart.
+		base := filepath.Join(p.outDir, fmt.Sprintf("%s_%d_peer%d", unsafeFileChars.ReplaceAllString(inbound.Remark, "_"), inbound.Port, i+1))
+		if err := os.WriteFile(base+".conf", []byte(conf), 0600); err != nil {
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Could not write %s.conf: %v\n"+utils.ColorReset, base, err)
+			continue
+		}
+		// The QR code holds the private key too, so it gets the same 0600 mode as the config.
+		png, err := qrcode.Encode(conf, qrcode.Medium, 512)
+		if err == nil {
+			err = os.WriteFile(base+".png", png, 0600)
+		}
+		if err != nil {
+			fmt.Printf(" "+utils.ColorBrightYellow+"⚠️ Could not write %s.png: %v\n"+utils.ColorReset, base, err)
+		}
+		written++
+	}
+	fmt.Printf(" "+utils.ColorGreen+"📁 %d peer config(s) written to %s\n"+utils.ColorReset, written, p.outDir)
+}
+
+// peerConfig renders a wg-quick config for one peer.
+func (p *wireGuardPlan) peerConfig(inbound models.InboundData, mtu int, peer clients.WireGuardPeer, serverPublicKey string) string {
+	var b strings.Builder
+	b.WriteString("[Interface]\n")
+	fmt.Fprintf(&b, "PrivateKey = %s\n", peer.PrivateKey)
+	if len(peer.AllowedIPs) > 0 {
+		fmt.Fprintf(&b, "Address = %s\n", strings.Join(peer.AllowedIPs, ", "))
+	}
+	if p.dns != "" {
+		fmt.Fprintf(&b, "DNS = %s\n", p.dns)
+	}
+	if mtu > 0 {
+		fmt.Fprintf(&b, "MTU = %d\n", mtu)
+	}
+	b.WriteString("\n[Peer]\n")
+	fmt.Fprintf(&b, "PublicKey = %s\n", serverPublicKey)
+	if peer.PreSharedKey != "" {
+		fmt.Fprintf(&b, "PresharedKey = %s\n", peer.PreSharedKey)
+	}
+	b.WriteString("AllowedIPs = 0.0.0.0/0, ::/0\n")
+	fmt.Fprintf(&b, "Endpoint = %s\n", net.JoinHostPort(p.endpoint, strconv.Itoa(inbound.Port)))
+	if peer.KeepAlive > 0 {
+		fmt.Fprintf(&b, "PersistentKeepalive = %d\n", peer.KeepAlive)
+	}
+	return b.String()
+}
@@ -35,7 +35,8 @@ type Inbound struct {
 
 // InboundSettings is the inner structure of the 'settings' field.
 type InboundSettings struct {
-	Clients []ClientSetting `json:"clients"`
+	Clients  []ClientSetting `json:"clients"`
+	Accounts []Account       `json:"accounts"`
 }
 
 // ClientSetting represents a client within the settings section.
@@ -50,6 +51,14 @@ type ClientSetting struct {
 	SubID      string `json:"subId"`
 	TgID       string `json:"tgId"`
 	Reset      int    `json:"reset"`
+	Password   string `json:"password,omitempty"` // trojan and shadowsocks credential
+	Method     string `json:"method,omitempty"`   // shadowsocks cipher of this client
+}
+
+// Account is a user/password pair of a socks, http or mixed inbound.
+type Account struct {
+	User string `json:"user"`
+	Pass string `json:"pass"`
 }
 
 // ClientTraffic represents user traffic data (upload and download).
@@ -66,12 +75,16 @@ type ClientDetails struct {
 	ClientLimitIP       int     `json:"-"`
 	ClientTotalGB       int64   `json:"client_total_gb"` // in bytes
 	ClientExpiryTime    int64   `json:"client_expiry_time"`
-	ClientFlow          string  `json:"-"`
+	ClientFlow          string  `json:"client_flow,omitempty"`
 	ClientSubID         string  `json:"client_sub_id"`
+	ClientPassword      string  `json:"client_password,omitempty"` // trojan and shadowsocks credential
+	ClientMethod        string  `json:"client_method,omitempty"`   // shadowsocks cipher
 	ClientTgID          string  `json:"-"`
-	ClientReset         int     `json:"-"`
-	TrafficUsed         int64   `json:"traffic_used"`      // in bytes
-	TrafficRemaining    int64   `json:"traffic_remaining"` // in bytes (-1 for unlimited)
+	ClientReset         int     `json:"client_reset,omitempty"` // periodic usage reset in days, 0 = never
+	TrafficUsed         int64   `json:"traffic_used"`           // in bytes
+	TrafficUp           int64   `json:"traffic_up,omitempty"`   // in bytes, part of TrafficUsed
+	TrafficDown         int64   `json:"traffic_down,omitempty"` // in bytes, part of TrafficUsed
+	TrafficRemaining    int64   `json:"traffic_remaining"`      // in bytes (-1 for unlimited)
 	TrafficUsagePercent float64 `json:"-"`
 }
 
@@ -115,6 +128,7 @@ type InboundData struct {
 	ExternalProxy    string          `json:"external_proxy"`
 	OriginalSettings string          `json:"original_settings"` // Save raw settings for WireGuard
 	Clients          []ClientDetails `json:"clients"`
+	Accounts         []Account       `json:"accounts,omitempty"` // socks, http and mixed inbounds
 }
 
 // AddInboundPayload is the structure for the JSON body when adding an inbound.
@@ -149,23 +163,29 @@ type PasarGuardTokenResponse struct {
 
 // PasarGuardUser represents a user in PasarGuard panel.
 type PasarGuardUser struct {
-	ID               int                    `json:"id"`
-	Username         string                 `json:"username"`
-	Email            string                 `json:"email"`
-	UUID             string                 `json:"uuid"`
-	Enable           bool                   `json:"enable"`
-	TotalGB          int64                  `json:"totalGB"`    // Total traffic in bytes
-	ExpiryTime       int64                  `json:"expiryTime"` // As a timestamp
-	LimitIP          int                    `json:"limitIp"`
-	UsedTraffic      int64                  `json:"usedTraffic"`      // Used traffic in bytes
-	RemainingTraffic int64                  `json:"remainingTraffic"` // Remaining traffic in bytes
-	Protocol         string                 `json:"protocol"`
-	Port             int                    `json:"port"`
-	Remark           string                 `json:"remark"`
-	SubscriptionURL  string                 `json:"subscription_url"` // Subscription URL
-	Note             string                 `json:"note"`             // Note field (can contain email or other info)
-	ProxySettings    map[string]interface{} `json:"proxy_settings"`   // All protocol UUIDs
-	GroupIDs         []int                  `json:"group_ids"`
+	ID                     int                    `json:"id"`
+	Username               string                 `json:"username"`
+	Email                  string                 `json:"email"`
+	UUID                   string                 `json:"uuid"`
+	Enable                 bool                   `json:"enable"`
+	Status                 string                 `json:"status,omitempty"` // active, disabled, limited, expired or on_hold, as reported by the panel
+	TotalGB                int64                  `json:"totalGB"`          // Total traffic in bytes
+	ExpiryTime             int64                  `json:"expiryTime"`       // As a timestamp
+	LimitIP                int                    `json:"limitIp"`
+	UsedTraffic            int64                  `json:"usedTraffic"`      // Used traffic in bytes
+	RemainingTraffic       int64                  `json:"remainingTraffic"` // Remaining traffic in bytes
+	Protocol               string                 `json:"protocol"`
+	Port                   int                    `json:"port"`
+	Remark                 string                 `json:"remark"`
+	InboundTag             string                 `json:"inbound_tag,omitempty"` // 3X-UI inbound tag, used for group mapping
+	SubscriptionURL        string                 `json:"subscription_url"`      // Subscription URL
+	Note                   string                 `json:"note"`                  // Note field (can contain email or other info)
+	ProxySettings          map[string]interface{} `json:"proxy_settings"`        // All protocol UUIDs
+	GroupIDs               []int                  `json:"group_ids"`
+	OnHoldExpireDuration   int64                  `json:"on_hold_expire_duration,omitempty"`   // Seconds of validity counted from first use (status on_hold)
+	OnHoldTimeout          int64                  `json:"on_hold_timeout,omitempty"`           // Unix time after which an on_hold user starts expiring even without connecting
+	DataLimitResetStrategy string                 `json:"data_limit_reset_strategy,omitempty"` // no_reset, day, week, month or year
+	ResetDays              int                    `json:"reset_days,omitempty"`                // DataLimitResetStrategy as a 3X-UI reset period
 }
 
 // PasarGuardUserListResponse represents the response from getting users list.
@@ -193,7 +213,7 @@ type PasarGuardUserAPI struct {
 	DataLimitResetStrategy string                 `json:"data_limit_reset_strategy"`
 	Note                   *string                `json:"note"`
 	OnHoldExpireDuration   *int                   `json:"on_hold_expire_duration"`
-	OnHoldTimeout          *int                   `json:"on_hold_timeout"`
+	OnHoldTimeout          json.RawMessage        `json:"on_hold_timeout"` // ISO 8601 string or unix timestamp, depending on panel version
 	GroupIDs               []int                  `json:"group_ids"`
 	AutoDeleteInDays       *int                   `json:"auto_delete_in_days"`
 	NextPlan               *string                `json:"next_plan"`
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// PasarGuard data_limit_reset_strategy values.
+const (
+	ResetNone  = "no_reset"
+	ResetDay   = "day"
+	ResetWeek  = "week"
+	ResetMonth = "month"
+	ResetYear  = "year"
+)
+
+// resetPeriods is the length in days of each periodic strategy.
+var resetPeriods = map[string]int{
+	ResetDay:   1,
+	ResetWeek:  7,
+	ResetMonth: 30,
+	ResetYear:  365,
+}
+
+// ResetStrategyOverrides maps 3X-UI reset periods (days) that have no exact PasarGuard
+// equivalent to a chosen strategy. Periods not listed use the closest strategy.
+var ResetStrategyOverrides = map[int]string{}
+
+// ResetStrategyFromDays converts a 3X-UI client's reset period in days to a PasarGuard
+// data_limit_reset_strategy.
+func ResetStrategyFromDays(days int) string {
+	if days <= 0 {
+		return ResetNone
+	}
+	if strategy, ok := ResetStrategyOverrides[days]; ok {
+		return strategy
+	}
+	best, bestDistance := ResetNone, math.Inf(1)
+	for strategy, period := range resetPeriods {
+		// Compare ratios rather than differences, so 15 days maps to a month and 3 days to a week.
+		distance := math.Abs(math.Log(float64(days) / float64(period)))
+		if distance < bestDistance || distance == bestDistance && period > resetPeriods[best] {
+			best, bestDistance = strategy, distance
+		}
+	}
+	return best
+}
+
+// ResetDaysFromStrategy converts a PasarGuard data_limit_reset_strategy to a 3X-UI reset period in days.
+func ResetDaysFromStrategy(strategy string) int {
+	return resetPeriods[strategy]
+}
+
+// ParseResetStrategyOverrides parses a list like "15=month,3=week" into ResetStrategyOverrides.
+func ParseResetStrategyOverrides(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		daysText, strategy, found := strings.Cut(pair, "=")
+		days, err := strconv.Atoi(strings.TrimSpace(daysText))
+		strategy = strings.TrimSpace(strategy)
+		if !found || err != nil || days <= 0 {
+			return fmt.Errorf("invalid reset mapping '%s', expected <days>=<strategy>", pair)
+		}
+		if _, ok := resetPeriods[strategy]; !ok && strategy != ResetNone {
+			return fmt.Errorf("unknown reset strategy '%s'", strategy)
+		}
+		ResetStrategyOverrides[days] = strategy
+	}
+	return nil
+}
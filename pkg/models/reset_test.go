@@ -0,0 +1,45 @@
+package models
+
+import "testing"
+
+func TestResetStrategyFromDays(t *testing.T) {
+	tests := []struct {
+		days int
+		want string
+	}{
+		{-5, ResetNone},
+		{0, ResetNone},
+		{1, ResetDay},
+		{2, ResetDay},
+		{3, ResetWeek},
+		{7, ResetWeek},
+		{15, ResetMonth},
+		{30, ResetMonth},
+		{60, ResetMonth},
+		{180, ResetYear},
+		{365, ResetYear},
+		{1000, ResetYear},
+	}
+	for _, tt := range tests {
+		if got := ResetStrategyFromDays(tt.days); got != tt.want {
+			t.Errorf("ResetStrategyFromDays(%d) = %s, want %s", tt.days, got, tt.want)
+		}
+	}
+}
+
+func TestResetStrategyOverrides(t *testing.T) {
+	defer func() { ResetStrategyOverrides = map[int]string{} }()
+	if err := ParseResetStrategyOverrides("15=week, 3=no_reset"); err != nil {
+		t.Fatalf("ParseResetStrategyOverrides: %v", err)
+	}
+	for days, want := range map[int]string{15: ResetWeek, 3: ResetNone, 30: ResetMonth} {
+		if got := ResetStrategyFromDays(days); got != want {
+			t.Errorf("ResetStrategyFromDays(%d) = %s, want %s", days, got, want)
+		}
+	}
+	for _, bad := range []string{"15", "x=week", "0=week", "15=fortnight"} {
+		if err := ParseResetStrategyOverrides(bad); err == nil {
+			t.Errorf("ParseResetStrategyOverrides(%q) succeeded, want an error", bad)
+		}
+	}
+}
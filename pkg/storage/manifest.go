@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"panels_user_manager/pkg/models"
+)
+
+// ManifestSuffix is appended to an export file name to get its manifest file name.
+const ManifestSuffix = ".manifest.json"
+
+// Manifest describes an export file so it can be verified after being copied around.
+type Manifest struct {
+	File             string `json:"file"`
+	SHA256           string `json:"sha256"`
+	Size             int64  `json:"size"`
+	CreatedAt        string `json:"created_at"`
+	Kind             string `json:"kind"`
+	TotalInbounds    int    `json:"total_inbounds"`
+	TotalUsers       int    `json:"total_users"`
+	ActiveUsers      int    `json:"active_users"`
+	EnabledInbounds  int    `json:"enabled_inbounds"`
+	TrafficUsed      int64  `json:"traffic_used"`
+	TrafficAllocated int64  `json:"traffic_allocated"`
+	TrafficRemaining int64  `json:"traffic_remaining"`
+}
+
+// AddInbound accumulates the counts and traffic totals of a 3X-UI inbound and its clients.
+func (m *Manifest) AddInbound(inbound models.InboundData) {
+	m.TotalInbounds++
+	if inbound.Enable {
+		m.EnabledInbounds++
+	}
+	for _, client := range inbound.Clients {
+		m.addUser(client.ClientEnable, client.TrafficUsed, client.ClientTotalGB, client.TrafficRemaining)
+	}
+}
+
+// AddUser accumulates the counts and traffic totals of a PasarGuard-format user.
+func (m *Manifest) AddUser(user models.PasarGuardUser) {
+	m.addUser(user.Enable, user.UsedTraffic, user.TotalGB, user.RemainingTraffic)
+}
+
+func (m *Manifest) addUser(enabled bool, used, total, remaining int64) {
+	m.TotalUsers++
+	if enabled {
+		m.ActiveUsers++
+	}
+	m.TrafficUsed += used
+	if total > 0 {
+		m.TrafficAllocated += total
+	}
+	if remaining > 0 {
+		m.TrafficRemaining += remaining
+	}
+}
+
+// WriteManifest hashes the finished export file and writes its manifest next to it. Like the
+// export, the manifest is only readable by its owner.
+func WriteManifest(filename string, m Manifest) error {
+	sum, size, err := fileChecksum(filename)
+	if err != nil {
+		return fmt.Errorf("error hashing export file: %v", err)
+	}
+	m.File = filename
+	m.SHA256 = sum
+	m.Size = size
+	m.CreatedAt = time.Now().Format(time.RFC3339)
+	data, err := json.MarshalIndent(m, "", " ")
+	if err != nil {
+		return fmt.Errorf("error creating manifest JSON: %v", err)
+	}
+	if err := os.WriteFile(filename+ManifestSuffix, data, 0600); err != nil {
+		return fmt.Errorf("error saving manifest: %v", err)
+	}
+	if err := os.Chmod(filename+ManifestSuffix, 0600); err != nil {
+		return fmt.Errorf("error saving manifest: %v", err)
+	}
+	return nil
+}
+
+// ReadManifest loads the manifest belonging to filename. It returns os.ErrNotExist
+// (wrapped) when the export has no manifest.
+func ReadManifest(filename string) (*Manifest, error) {
+	data, err := os.ReadFile(filename + ManifestSuffix)
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("error parsing manifest: %v", err)
+	}
+	return &m, nil
+}
+
+// VerifyManifest checks filename against its manifest: first the checksum and size of the
+// file as stored on disk, then the record counts and traffic totals of its decoded content.
+// It returns one message per mismatch; an empty slice means the file is intact.
+func VerifyManifest(filename string, opts Options, m *Manifest) ([]string, error) {
+	var problems []string
+	sum, size, err := fileChecksum(filename)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing file: %v", err)
+	}
+	if size != m.Size {
+		problems = append(problems, fmt.Sprintf("size: manifest %d bytes, file %d bytes", m.Size, size))
+	}
+	if sum != m.SHA256 {
+		problems = append(problems, fmt.Sprintf("sha256: manifest %s, file %s", m.SHA256, sum))
+	}
+
+	var actual Manifest
+	switch m.Kind {
+	case KindInbounds:
+		err = scanRecords(filename, opts, KindInbounds, actual.AddInbound)
+	case KindUsers:
+		err = scanRecords(filename, opts, KindUsers, actual.AddUser)
+	default:
+		return nil, fmt.Errorf("unknown manifest kind '%s'", m.Kind)
+	}
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("content: %v", err))
+		return problems, nil
+	}
+
+	compare := func(field string, want, got int64) {
+		if want != got {
+			problems = append(problems, fmt.Sprintf("%s: manifest %d, file %d", field, want, got))
+		}
+	}
+	compare("total_inbounds", int64(m.TotalInbounds), int64(actual.TotalInbounds))
+	compare("enabled_inbounds", int64(m.EnabledInbounds), int64(actual.EnabledInbounds))
+	compare("total_users", int64(m.TotalUsers), int64(actual.TotalUsers))
+	compare("active_users", int64(m.ActiveUsers), int64(actual.ActiveUsers))
+	compare("traffic_used", m.TrafficUsed, actual.TrafficUsed)
+	compare("traffic_allocated", m.TrafficAllocated, actual.TrafficAllocated)
+	compare("traffic_remaining", m.TrafficRemaining, actual.TrafficRemaining)
+	return problems, nil
+}
+
+// scanRecords decodes every record of the given kind and hands it to fn.
+func scanRecords[T any](filename string, opts Options, kind string, fn func(T)) error {
+	records, err := OpenRecords[T](filename, opts, kind)
+	if err != nil {
+		return err
+	}
+	defer records.Close()
+	for {
+		record, err := records.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		fn(record)
+	}
+}
+
+// fileChecksum returns the hex SHA-256 and size of the raw file bytes.
+func fileChecksum(filename string) (string, int64, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
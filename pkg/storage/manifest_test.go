@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"panels_user_manager/pkg/models"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	users := []models.PasarGuardUser{
+		{Username: "john", Enable: true, TotalGB: 100, UsedTraffic: 40, RemainingTraffic: 60},
+		{Username: "mary", UsedTraffic: 5},
+	}
+	filename := filepath.Join(t.TempDir(), "users.json")
+	data, err := json.Marshal(models.PasarGuardUsersExportFile{TotalUsers: len(users), Users: users})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(filename, data, Options{}); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	manifest := Manifest{Kind: KindUsers}
+	for _, user := range users {
+		manifest.AddUser(user)
+	}
+	if err := WriteManifest(filename, manifest); err != nil {
+		t.Fatalf("WriteManifest: %v", err)
+	}
+	info, err := os.Stat(filename + ManifestSuffix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("manifest mode = %o, want 600", mode)
+	}
+
+	m, err := ReadManifest(filename)
+	if err != nil {
+		t.Fatalf("ReadManifest: %v", err)
+	}
+	if m.TotalUsers != 2 || m.ActiveUsers != 1 || m.TrafficUsed != 45 || m.TrafficAllocated != 100 || m.TrafficRemaining != 60 {
+		t.Errorf("manifest = %+v", m)
+	}
+	problems, err := VerifyManifest(filename, Options{}, m)
+	if err != nil || len(problems) > 0 {
+		t.Fatalf("VerifyManifest = %v, %v, want no problems", problems, err)
+	}
+
+	// A changed record keeps the file valid JSON but no longer matches the manifest.
+	users[1].UsedTraffic = 6
+	data, err = json.Marshal(models.PasarGuardUsersExportFile{TotalUsers: len(users), Users: users})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(filename, data, Options{}); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	problems, err = VerifyManifest(filename, Options{}, m)
+	if err != nil {
+		t.Fatalf("VerifyManifest: %v", err)
+	}
+	if len(problems) != 2 {
+		t.Errorf("problems = %q, want the checksum and traffic_used mismatches", problems)
+	}
+}
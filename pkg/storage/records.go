@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FormatNDJSON marks the header line of a streaming (one record per line) export file.
+const FormatNDJSON = "ndjson"
+
+// Record kinds stored in export files, matching the array key of the regular JSON layout.
+const (
+	KindInbounds = "inbounds"
+	KindUsers    = "users"
+)
+
+// Header holds the top-level metadata of an export file. In NDJSON files it is the first line;
+// in regular JSON files it is assembled from the fields preceding the record array.
+type Header struct {
+	Format        string `json:"format,omitempty"`
+	Kind          string `json:"kind,omitempty"`
+	ExportDate    string `json:"export_date"`
+	PanelType     string `json:"panel_type,omitempty"`
+	TotalInbounds int    `json:"total_inbounds,omitempty"`
+	TotalUsers    int    `json:"total_users"`
+}
+
+// RecordWriter writes an NDJSON export file incrementally, one record per line.
+type RecordWriter struct {
+	w   io.WriteCloser
+	enc *json.Encoder
+}
+
+// NewRecordWriter creates filename and writes the NDJSON header line.
+func NewRecordWriter(filename string, opts Options, header Header) (*RecordWriter, error) {
+	w, err := Create(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	header.Format = FormatNDJSON
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(header); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("error writing header: %v", err)
+	}
+	return &RecordWriter{w: w, enc: enc}, nil
+}
+
+// Write appends a single record as one line.
+func (rw *RecordWriter) Write(record interface{}) error {
+	return rw.enc.Encode(record)
+}
+
+// Close flushes all layers and closes the file.
+func (rw *RecordWriter) Close() error {
+	return rw.w.Close()
+}
+
+// RecordReader decodes the records of an export file one at a time, supporting both
+// the regular JSON document layout and the NDJSON streaming layout.
+type RecordReader[T any] struct {
+	r      io.ReadCloser
+	dec    *json.Decoder
+	header Header
+	inList bool // true while iterating a JSON array, false for NDJSON lines
+	done   bool
+}
+
+// OpenRecords opens filename and positions the reader at the first record of the given kind.
+func OpenRecords[T any](filename string, opts Options, kind string) (*RecordReader[T], error) {
+	r, err := Open(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	rr := &RecordReader[T]{r: r, dec: json.NewDecoder(r)}
+	if err := rr.readHeader(kind); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return rr, nil
+}
+
+// Header returns the file metadata read before the records.
+func (rr *RecordReader[T]) Header() Header {
+	return rr.header
+}
+
+// More reports whether another record is available.
+func (rr *RecordReader[T]) More() bool {
+	return !rr.done && rr.dec.More()
+}
+
+// Next decodes the next record. It returns io.EOF once all records have been read, or an
+// error if a JSON document ends before its record list is closed (a truncated file).
+func (rr *RecordReader[T]) Next() (T, error) {
+	var record T
+	if rr.done {
+		return record, io.EOF
+	}
+	if !rr.dec.More() {
+		rr.done = true
+		if rr.inList {
+			if _, err := rr.dec.Token(); err != nil {
+				return record, fmt.Errorf("file appears to be truncated: %v", err)
+			}
+		}
+		return record, io.EOF
+	}
+	if err := rr.dec.Decode(&record); err != nil {
+		return record, fmt.Errorf("error decoding record: %v", err)
+	}
+	return record, nil
+}
+
+// Close closes the underlying file.
+func (rr *RecordReader[T]) Close() error {
+	return rr.r.Close()
+}
+
+// readHeader walks the top-level object token by token. For regular JSON it stops at the
+// record array; for NDJSON it consumes the whole header line.
+func (rr *RecordReader[T]) readHeader(kind string) error {
+	tok, err := rr.dec.Token()
+	if err != nil {
+		return fmt.Errorf("error reading file header: %v", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return fmt.Errorf("invalid export file: expected a JSON object")
+	}
+
+	fields := make(map[string]json.RawMessage)
+	for rr.dec.More() {
+		tok, err := rr.dec.Token()
+		if err != nil {
+			return fmt.Errorf("error reading file header: %v", err)
+		}
+		key, _ := tok.(string)
+		if key == kind {
+			tok, err := rr.dec.Token()
+			if err != nil {
+				return fmt.Errorf("error reading '%s' list: %v", kind, err)
+			}
+			if tok == nil {
+				rr.done = true
+			} else if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+				return fmt.Errorf("invalid export file: '%s' is not a list", kind)
+			}
+			rr.inList = true
+			break
+		}
+		var raw json.RawMessage
+		if err := rr.dec.Decode(&raw); err != nil {
+			return fmt.Errorf("error reading header field '%s': %v", key, err)
+		}
+		fields[key] = raw
+	}
+
+	headerBytes, _ := json.Marshal(fields)
+	if err := json.Unmarshal(headerBytes, &rr.header); err != nil {
+		return fmt.Errorf("error parsing file header: %v", err)
+	}
+	if rr.inList {
+		return nil
+	}
+	if rr.header.Format != FormatNDJSON {
+		return fmt.Errorf("invalid export file: no '%s' found", kind)
+	}
+	if rr.header.Kind != "" && rr.header.Kind != kind {
+		return fmt.Errorf("invalid export file: contains %s, expected %s", rr.header.Kind, kind)
+	}
+	if _, err := rr.dec.Token(); err != nil {
+		return fmt.Errorf("error reading file header: %v", err)
+	}
+	return nil
+}
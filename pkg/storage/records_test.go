@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type testRecord struct {
+	Username string `json:"username"`
+	Used     int64  `json:"used"`
+}
+
+func TestRecordsRoundTrip(t *testing.T) {
+	records := []testRecord{{"john", 10}, {"mary", 20}, {"bob", 0}}
+	header := Header{Kind: KindUsers, ExportDate: "2024-06-01T00:00:00Z", PanelType: "PasarGuard", TotalUsers: len(records)}
+
+	tests := []struct {
+		name      string
+		opts      Options
+		wantMagic []byte
+	}{
+		{"json", Options{}, []byte("{")},
+		{"json gzip", Options{Compression: CompressionGzip}, gzipMagic},
+		{"json zstd", Options{Compression: CompressionZstd}, zstdMagic},
+		{"ndjson", Options{Streaming: true}, []byte("{")},
+		{"ndjson gzip", Options{Streaming: true, Compression: CompressionGzip}, gzipMagic},
+		{"ndjson zstd", Options{Streaming: true, Compression: CompressionZstd}, zstdMagic},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "users"+tt.opts.Extension())
+			if tt.opts.Streaming {
+				rw, err := NewRecordWriter(filename, tt.opts, header)
+				if err != nil {
+					t.Fatalf("NewRecordWriter: %v", err)
+				}
+				for _, record := range records {
+					if err := rw.Write(record); err != nil {
+						t.Fatalf("Write: %v", err)
+					}
+				}
+				if err := rw.Close(); err != nil {
+					t.Fatalf("Close: %v", err)
+				}
+			} else {
+				document := map[string]interface{}{"export_date": header.ExportDate, "panel_type": header.PanelType, "total_users": header.TotalUsers, "users": records}
+				data, err := json.MarshalIndent(document, "", " ")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := WriteFile(filename, data, tt.opts); err != nil {
+					t.Fatalf("WriteFile: %v", err)
+				}
+			}
+
+			raw, err := os.ReadFile(filename)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.HasPrefix(raw, tt.wantMagic) {
+				t.Errorf("file starts with %x, want %x", raw[:4], tt.wantMagic)
+			}
+
+			// Compression is detected from the content, so reading needs no options.
+			rr, err := OpenRecords[testRecord](filename, Options{}, KindUsers)
+			if err != nil {
+				t.Fatalf("OpenRecords: %v", err)
+			}
+			defer rr.Close()
+			if got := rr.Header(); got.TotalUsers != header.TotalUsers || got.PanelType != header.PanelType {
+				t.Errorf("header = %+v, want %+v", got, header)
+			}
+			var got []testRecord
+			for {
+				record, err := rr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Next: %v", err)
+				}
+				got = append(got, record)
+			}
+			if len(got) != len(records) {
+				t.Fatalf("read %d record(s), want %d", len(got), len(records))
+			}
+			for i := range got {
+				if got[i] != records[i] {
+					t.Errorf("record %d = %+v, want %+v", i, got[i], records[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRecordsTruncated(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "users.json")
+	if err := os.WriteFile(filename, []byte(`{"total_users": 2, "users": [{"username": "john"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	rr, err := OpenRecords[testRecord](filename, Options{}, KindUsers)
+	if err != nil {
+		t.Fatalf("OpenRecords: %v", err)
+	}
+	defer rr.Close()
+	if _, err := rr.Next(); err != nil {
+		t.Fatalf("first record: %v", err)
+	}
+	if _, err := rr.Next(); err == nil || err == io.EOF {
+		t.Errorf("Next = %v, want a truncation error", err)
+	}
+}
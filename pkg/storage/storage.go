@@ -0,0 +1,349 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/klauspost/compress/zstd"
+)
+
+// IdentityFile is an optional age identity file used to decrypt exports without prompting.
+var IdentityFile = ""
+
+// ageHeader is the first line of every binary age file.
+const ageHeader = "age-encryption.org/v1"
+
+// Supported compression algorithms for export files.
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// Options controls how export files are written and read.
+type Options struct {
+	Passphrase  string   // Passphrase for scrypt-based encryption/decryption
+	Recipients  []string // age X25519 public keys (age1...) to encrypt to
+	Identities  []string // age secret keys (AGE-SECRET-KEY-1...) or identity file paths to decrypt with
+	Compression string   // CompressionNone, CompressionGzip or CompressionZstd (detected automatically on read)
+	Streaming   bool     // Write NDJSON (one record per line) instead of a single JSON document
+}
+
+// Encrypted reports whether files written with these options will be encrypted.
+func (o Options) Encrypted() bool {
+	return o.Passphrase != "" || len(o.Recipients) > 0
+}
+
+// KeyOptions builds read options from a single user-supplied key: an age secret key,
+// a path to an age identity file, or a passphrase.
+func KeyOptions(key string) Options {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, "AGE-SECRET-KEY-") {
+		return Options{Identities: []string{key}}
+	}
+	if info, err := os.Stat(key); err == nil && !info.IsDir() {
+		return Options{Identities: []string{key}}
+	}
+	return Options{Passphrase: key}
+}
+
+// Extension returns the file name suffix matching the chosen format and compression.
+func (o Options) Extension() string {
+	ext := ".json"
+	if o.Streaming {
+		ext = ".ndjson"
+	}
+	switch o.Compression {
+	case CompressionGzip:
+		ext += ".gz"
+	case CompressionZstd:
+		ext += ".zst"
+	}
+	if o.Encrypted() {
+		ext += ".age"
+	}
+	return ext
+}
+
+// Create opens filename for writing, wrapping it in compression and age encryption when requested.
+// Data is compressed first and then encrypted. Export files hold every user's UUID, so they are
+// always created with mode 0600.
+func Create(filename string, opts Options) (io.WriteCloser, error) {
+	recipients, err := opts.recipients()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Compression != CompressionNone && opts.Compression != CompressionGzip && opts.Compression != CompressionZstd {
+		return nil, fmt.Errorf("unsupported compression '%s'", opts.Compression)
+	}
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	// OpenFile only applies the mode to new files; an overwritten export keeps its old one.
+	if err := file.Chmod(0600); err != nil {
+		file.Close()
+		return nil, err
+	}
+	bufWriter := bufio.NewWriter(file)
+	closers := []io.Closer{flushCloser{bufWriter}, file}
+	var w io.Writer = bufWriter
+
+	if len(recipients) > 0 {
+		encWriter, err := age.Encrypt(w, recipients...)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error initializing encryption: %v", err)
+		}
+		closers = append([]io.Closer{encWriter}, closers...)
+		w = encWriter
+	}
+
+	switch opts.Compression {
+	case CompressionGzip:
+		gzWriter := gzip.NewWriter(w)
+		closers = append([]io.Closer{gzWriter}, closers...)
+		w = gzWriter
+	case CompressionZstd:
+		zstdWriter, err := zstd.NewWriter(w)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error initializing zstd compression: %v", err)
+		}
+		closers = append([]io.Closer{zstdWriter}, closers...)
+		w = zstdWriter
+	}
+	return &chainWriter{Writer: w, closers: closers}, nil
+}
+
+// WriteFile writes data to filename using the given options.
+func WriteFile(filename string, data []byte, opts Options) error {
+	w, err := Create(filename, opts)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// IsEncrypted reports whether filename is an age-encrypted file (binary or armored).
+func IsEncrypted(filename string) (bool, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+	kind, _, err := detectEncryption(bufio.NewReader(file))
+	if err != nil {
+		return false, err
+	}
+	return kind != "", nil
+}
+
+// Open opens filename for reading, transparently decrypting and decompressing it.
+// Encryption and compression are detected from the file content, not its name.
+func Open(filename string, opts Options) (io.ReadCloser, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := decrypt(filename, file, opts)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(plain)
+	head, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		file.Close()
+		return nil, fmt.Errorf("error reading '%s': %v", filename, err)
+	}
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		gzReader, err := gzip.NewReader(br)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error opening gzip stream in '%s': %v", filename, err)
+		}
+		return &chainReader{Reader: gzReader, closers: []io.Closer{gzReader, file}}, nil
+	case bytes.HasPrefix(head, zstdMagic):
+		zstdReader, err := zstd.NewReader(br)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("error opening zstd stream in '%s': %v", filename, err)
+		}
+		return &chainReader{Reader: zstdReader, closers: []io.Closer{zstdReader.IOReadCloser(), file}}, nil
+	}
+	return &chainReader{Reader: br, closers: []io.Closer{file}}, nil
+}
+
+// decrypt returns a reader yielding the decrypted content of file, or the file itself if it is not encrypted.
+func decrypt(filename string, file io.Reader, opts Options) (io.Reader, error) {
+	kind, br, err := detectEncryption(bufio.NewReader(file))
+	if err != nil {
+		return nil, err
+	}
+	if kind == "" {
+		return br, nil
+	}
+
+	identities, err := opts.identities()
+	if err != nil {
+		return nil, err
+	}
+	if len(identities) == 0 {
+		return nil, fmt.Errorf("file '%s' is encrypted, a passphrase or age identity is required", filename)
+	}
+	var src io.Reader = br
+	if kind == "armor" {
+		src = armor.NewReader(br)
+	}
+	decReader, err := age.Decrypt(src, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting '%s': %v", filename, err)
+	}
+	return decReader, nil
+}
+
+// ReadFile reads the whole content of filename, decrypting and decompressing it as needed.
+func ReadFile(filename string, opts Options) ([]byte, error) {
+	r, err := Open(filename, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// detectEncryption peeks at the beginning of the stream and returns "binary", "armor" or "".
+func detectEncryption(br *bufio.Reader) (string, *bufio.Reader, error) {
+	head, err := br.Peek(len(armor.Header))
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", br, err
+	}
+	switch {
+	case bytes.HasPrefix(head, []byte(ageHeader)):
+		return "binary", br, nil
+	case bytes.HasPrefix(head, []byte(armor.Header)):
+		return "armor", br, nil
+	}
+	return "", br, nil
+}
+
+// recipients converts the encryption options into age recipients.
+func (o Options) recipients() ([]age.Recipient, error) {
+	if o.Passphrase != "" && len(o.Recipients) > 0 {
+		return nil, fmt.Errorf("a passphrase cannot be combined with age recipients")
+	}
+	if o.Passphrase != "" {
+		r, err := age.NewScryptRecipient(o.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("error creating passphrase recipient: %v", err)
+		}
+		return []age.Recipient{r}, nil
+	}
+	var out []age.Recipient
+	for _, key := range o.Recipients {
+		r, err := age.ParseX25519Recipient(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient '%s': %v", key, err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// identities converts the decryption options into age identities.
+func (o Options) identities() ([]age.Identity, error) {
+	var out []age.Identity
+	if o.Passphrase != "" {
+		id, err := age.NewScryptIdentity(o.Passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("error creating passphrase identity: %v", err)
+		}
+		out = append(out, id)
+	}
+	keys := o.Identities
+	if len(keys) == 0 && IdentityFile != "" {
+		keys = []string{IdentityFile}
+	}
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if strings.HasPrefix(key, "AGE-SECRET-KEY-") {
+			id, err := age.ParseX25519Identity(key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid age secret key: %v", err)
+			}
+			out = append(out, id)
+			continue
+		}
+		f, err := os.Open(key)
+		if err != nil {
+			return nil, fmt.Errorf("error opening identity file '%s': %v", key, err)
+		}
+		ids, err := age.ParseIdentities(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing identity file '%s': %v", key, err)
+		}
+		out = append(out, ids...)
+	}
+	return out, nil
+}
+
+// chainWriter closes a stack of writers in order once writing is finished.
+type chainWriter struct {
+	io.Writer
+	closers []io.Closer
+}
+
+func (w *chainWriter) Close() error {
+	var firstErr error
+	for _, c := range w.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// flushCloser flushes a buffered writer when the writer chain is closed.
+type flushCloser struct {
+	w *bufio.Writer
+}
+
+func (f flushCloser) Close() error {
+	return f.w.Flush()
+}
+
+// chainReader closes the underlying file(s) of a layered reader.
+type chainReader struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *chainReader) Close() error {
+	var firstErr error
+	for _, c := range r.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
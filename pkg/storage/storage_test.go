@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+)
+
+func TestEncryptedRoundTrip(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`{"users":[{"username":"john","uuid":"aaaa-1111"}]}`)
+
+	tests := []struct {
+		name     string
+		write    Options
+		readKey  string
+		wantEnc  bool
+		wantFail bool
+	}{
+		{"plain", Options{}, "", false, false},
+		{"passphrase", Options{Passphrase: "correct horse"}, "correct horse", true, false},
+		{"wrong passphrase", Options{Passphrase: "correct horse"}, "battery staple", true, true},
+		{"recipient", Options{Recipients: []string{identity.Recipient().String()}}, identity.String(), true, false},
+		{"missing key", Options{Passphrase: "correct horse"}, "", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "export.json")
+			if err := WriteFile(filename, data, tt.write); err != nil {
+				t.Fatalf("WriteFile: %v", err)
+			}
+			encrypted, err := IsEncrypted(filename)
+			if err != nil || encrypted != tt.wantEnc {
+				t.Fatalf("IsEncrypted = %v, %v, want %v", encrypted, err, tt.wantEnc)
+			}
+			var opts Options
+			if tt.readKey != "" {
+				opts = KeyOptions(tt.readKey)
+			}
+			got, err := ReadFile(filename, opts)
+			if tt.wantFail {
+				if err == nil {
+					t.Fatalf("ReadFile succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadFile: %v", err)
+			}
+			if string(got) != string(data) {
+				t.Errorf("ReadFile = %q, want %q", got, data)
+			}
+		})
+	}
+}
+
+func TestCreateRestrictsMode(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "export.json")
+	if err := os.WriteFile(filename, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFile(filename, []byte("new"), Options{}); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("mode = %o, want 600 for an overwritten export", mode)
+	}
+}
@@ -0,0 +1,295 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// DefaultReconcileStateFile is where traffic baselines are kept when no other file is given.
+const DefaultReconcileStateFile = "reconcile_state.json"
+
+// TrafficBaseline is taken the first time a user is seen on both panels. From then on, usage on
+// either panel is measured against these counters and subtracted from the remaining allowance.
+type TrafficBaseline struct {
+	Remaining       int64  `json:"remaining"`                  // allowance left when the baseline was taken, in bytes
+	BaseThreeXUI    int64  `json:"base_threexui"`              // 3X-UI used traffic at baseline
+	BasePasarGuard  int64  `json:"base_pasarguard"`            // PasarGuard used traffic at baseline
+	QuotaThreeXUI   int64  `json:"quota_threexui"`             // 3X-UI quota as left by the last run
+	QuotaPasarGuard int64  `json:"quota_pasarguard,omitempty"` // PasarGuard data limit as left by the last run
+	Disabled        bool   `json:"disabled,omitempty"`         // disabled by the reconciler when the allowance ran out
+	TakenAt         string `json:"taken_at"`
+}
+
+// ReconcileState is persisted between reconciliation runs.
+type ReconcileState struct {
+	LastRun string                     `json:"last_run"`
+	Users   map[string]TrafficBaseline `json:"users"` // keyed by PasarGuard username
+}
+
+// ReconcileEntry is the outcome for one user present on both panels.
+type ReconcileEntry struct {
+	Username       string
+	UsedThreeXUI   int64
+	UsedPasarGuard int64
+	Combined       int64 // consumption on both panels since the baseline
+	Remaining      int64
+	Changed        bool // a new limit was (or, in a dry run, would be) pushed
+	Err            error
+}
+
+// ReconcileReport summarises a reconciliation run.
+type ReconcileReport struct {
+	Entries   []ReconcileEntry
+	Unlimited int // skipped because 3X-UI has no quota for them
+	OnlyOne   int // skipped because they exist on one panel only
+}
+
+// Reconciler keeps the remaining quota of users that exist on both panels consistent, so traffic
+// consumed on one panel is deducted on the other as well.
+type Reconciler struct {
+	source    *clients.ThreeXUIClient
+	target    *clients.PasarGuardClient
+	stateFile string
+	state     *ReconcileState
+}
+
+// threeXUIClientRef points to a raw client object inside an inbound's settings.
+type threeXUIClientRef struct {
+	inbound models.Inbound
+	client  map[string]interface{}
+}
+
+// NewReconciler creates a Reconciler and loads the baselines of previous runs from stateFile.
+func NewReconciler(source *clients.ThreeXUIClient, target *clients.PasarGuardClient, stateFile string) (*Reconciler, error) {
+	if stateFile == "" {
+		stateFile = DefaultReconcileStateFile
+	}
+	state := &ReconcileState{}
+	if err := readJSON(stateFile, state); err != nil {
+		return nil, err
+	}
+	if state.Users == nil {
+		state.Users = make(map[string]TrafficBaseline)
+	}
+	return &Reconciler{source: source, target: target, stateFile: stateFile, state: state}, nil
+}
+
+// Run computes each shared user's combined consumption and pushes a limit of
+// "own usage + remaining allowance" to both panels. The allowance is taken from the 3X-UI quota
+// when the user is first seen. A user whose allowance is used up is disabled instead of getting
+// a zero limit, which both panels treat as unlimited. With dryRun nothing is pushed or saved.
+func (r *Reconciler) Run(dryRun bool) (*ReconcileReport, error) {
+	if err := r.source.Login(); err != nil {
+		return nil, fmt.Errorf("3X-UI login failed: %v", err)
+	}
+	if err := r.target.Login(); err != nil {
+		return nil, fmt.Errorf("PasarGuard login failed: %v", err)
+	}
+	inbounds, err := r.source.GetAllInbounds()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching inbounds: %v", err)
+	}
+	pgUsers, err := r.target.GetAllUsers()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching PasarGuard users: %v", err)
+	}
+	byUsername := make(map[string]models.PasarGuardUser)
+	for _, u := range pgUsers {
+		byUsername[strings.ToLower(strings.TrimSpace(u.Username))] = u
+	}
+
+	// 3X-UI tracks traffic per email, so every client sharing an email is updated together.
+	var order []string
+	refs := make(map[string][]threeXUIClientRef)
+	for _, inbound := range inbounds {
+		var settings struct {
+			Clients []map[string]interface{} `json:"clients"`
+		}
+		if strings.TrimSpace(inbound.Settings) == "" || json.Unmarshal([]byte(inbound.Settings), &settings) != nil {
+			continue
+		}
+		for _, client := range settings.Clients {
+			email, _ := client["email"].(string)
+			if email == "" {
+				continue
+			}
+			if _, exists := refs[email]; !exists {
+				order = append(order, email)
+			}
+			refs[email] = append(refs[email], threeXUIClientRef{inbound: inbound, client: client})
+		}
+	}
+
+	report := &ReconcileReport{}
+	seen := make(map[string]bool)
+	for _, email := range order {
+		username := sanitizeUsername(email)
+		pgUser, ok := byUsername[username]
+		if !ok {
+			report.OnlyOne++
+			continue
+		}
+		total := int64(numberField(refs[email][0].client, "totalGB"))
+		if total <= 0 {
+			report.Unlimited++
+			continue
+		}
+		traffic, err := r.source.GetClientTraffic(email)
+		if err != nil {
+			report.Entries = append(report.Entries, ReconcileEntry{Username: username, Err: fmt.Errorf("error fetching 3X-UI traffic: %v", err)})
+			continue
+		}
+		seen[username] = true
+		used3x := traffic.Up + traffic.Down
+		usedPG := pgUser.UsedTraffic
+
+		base, known := r.state.Users[username]
+		if !known {
+			base = TrafficBaseline{Remaining: total - used3x, BaseThreeXUI: used3x, BasePasarGuard: usedPG, TakenAt: time.Now().Format(time.RFC3339)}
+			if base.Remaining < 0 {
+				base.Remaining = 0
+			}
+		}
+		// A quota changed on either panel since the last run (e.g. a renewal) changes the allowance by the same amount.
+		if known && total != base.QuotaThreeXUI {
+			base.Remaining += total - base.QuotaThreeXUI
+		}
+		if known && base.QuotaPasarGuard > 0 && pgUser.TotalGB > 0 && pgUser.TotalGB != base.QuotaPasarGuard {
+			base.Remaining += pgUser.TotalGB - base.QuotaPasarGuard
+		}
+		if base.Remaining < 0 {
+			base.Remaining = 0
+		}
+		// A counter lower than its baseline was reset on that panel; count from zero.
+		if used3x < base.BaseThreeXUI {
+			base.BaseThreeXUI = 0
+		}
+		if usedPG < base.BasePasarGuard {
+			base.BasePasarGuard = 0
+		}
+		entry := ReconcileEntry{
+			Username:       username,
+			UsedThreeXUI:   used3x,
+			UsedPasarGuard: usedPG,
+			Combined:       (used3x - base.BaseThreeXUI) + (usedPG - base.BasePasarGuard),
+		}
+		entry.Remaining = base.Remaining - entry.Combined
+		if entry.Remaining < 0 {
+			entry.Remaining = 0
+		}
+
+		limit3x, limitPG := used3x+entry.Remaining, usedPG+entry.Remaining
+		for _, ref := range refs[email] {
+			enabled, _ := ref.client["enable"].(bool)
+			quota := int64(numberField(ref.client, "totalGB"))
+			// With allowance left, clients disabled by this reconciler or by 3X-UI for running
+			// out of traffic are enabled again.
+			reenable := !enabled && entry.Remaining > 0 && (base.Disabled || quota > 0 && used3x >= quota)
+			if limit3x == 0 && !enabled || limit3x > 0 && quota == limit3x && !reenable {
+				continue
+			}
+			entry.Changed = true
+			if dryRun {
+				continue
+			}
+			if limit3x > 0 {
+				ref.client["totalGB"] = limit3x
+				if reenable {
+					ref.client["enable"] = true
+				}
+			} else {
+				ref.client["enable"] = false
+			}
+			if err := r.source.UpdateClient(ref.inbound.ID, clients.ClientKey(ref.inbound.Protocol, ref.client), ref.client); err != nil {
+				entry.Err = fmt.Errorf("error updating 3X-UI client in '%s': %v", ref.inbound.Remark, err)
+			}
+		}
+		// Limited and expired users are not disabled by an admin and must not be sent as disabled.
+		// With allowance left, limited users and the users this reconciler disabled are enabled
+		// again; expired users stay expired until their expiry is extended.
+		depletedPG := pgUser.Status == "limited" || pgUser.Status == "expired"
+		reenablePG := !pgUser.Enable && entry.Remaining > 0 && (pgUser.Status == "limited" || base.Disabled)
+		if limitPG > 0 && (pgUser.TotalGB != limitPG || reenablePG) || limitPG == 0 && pgUser.Enable {
+			entry.Changed = true
+			if !dryRun {
+				update := pgUser
+				update.UsedTraffic = -1 // only the limit changes, never the panel's own counter
+				if limitPG > 0 {
+					update.TotalGB = limitPG
+					update.Enable = pgUser.Enable || depletedPG || reenablePG
+				} else {
+					update.Enable = false
+				}
+				if err := r.target.UpdateUserByIdentifier(pgUser.Username, update); err != nil && entry.Err == nil {
+					entry.Err = fmt.Errorf("error updating PasarGuard user: %v", err)
+				}
+			}
+		}
+		report.Entries = append(report.Entries, entry)
+
+		base.BaseThreeXUI, base.BasePasarGuard, base.Remaining = used3x, usedPG, entry.Remaining
+		base.QuotaThreeXUI, base.QuotaPasarGuard = total, pgUser.TotalGB
+		base.Disabled = limit3x == 0 || limitPG == 0 || base.Disabled && entry.Remaining == 0
+		if limit3x > 0 {
+			base.QuotaThreeXUI = limit3x
+		}
+		if limitPG > 0 {
+			base.QuotaPasarGuard = limitPG
+		}
+		if entry.Err == nil {
+			r.state.Users[username] = base
+		}
+	}
+
+	if dryRun {
+		return report, nil
+	}
+	for username := range r.state.Users {
+		if !seen[username] {
+			delete(r.state.Users, username)
+		}
+	}
+	r.state.LastRun = time.Now().Format(time.RFC3339)
+	return report, writeJSON(r.stateFile, r.state)
+}
+
+// PrintReconcileReport prints one line per reconciled user and a summary.
+func PrintReconcileReport(report *ReconcileReport, dryRun bool) {
+	changed, failed := 0, 0
+	fmt.Printf("\n %-25s %12s %12s %12s %12s\n", "USER", "3X-UI", "PASARGUARD", "COMBINED", "REMAINING")
+	for _, e := range report.Entries {
+		if e.Err != nil {
+			failed++
+			fmt.Printf(" "+utils.ColorRed+"%-25s ✗ %v"+utils.ColorReset+"\n", e.Username[:utils.Min(25, len(e.Username))], e.Err)
+			continue
+		}
+		color := utils.ColorDim
+		if e.Changed {
+			changed++
+			color = utils.ColorYellow
+		}
+		fmt.Printf(" "+color+"%-25s %12s %12s %12s %12s"+utils.ColorReset+"\n", e.Username[:utils.Min(25, len(e.Username))],
+			utils.FormatBytes(e.UsedThreeXUI), utils.FormatBytes(e.UsedPasarGuard), utils.FormatBytes(e.Combined), utils.FormatBytes(e.Remaining))
+	}
+	verb := "updated"
+	if dryRun {
+		verb = "would be updated"
+	}
+	fmt.Printf("\n "+utils.ColorBrightGreen+"✓ %d user(s) %s, %d already consistent"+utils.ColorReset+"\n", changed, verb, len(report.Entries)-changed-failed)
+	if failed > 0 {
+		fmt.Printf(" "+utils.ColorRed+"✗ %d user(s) failed"+utils.ColorReset+"\n", failed)
+	}
+	fmt.Printf(" "+utils.ColorDim+"Skipped: %d unlimited, %d present on one panel only"+utils.ColorReset+"\n", report.Unlimited, report.OnlyOne)
+}
+
+// numberField reads a JSON number from a raw object; missing or non-numeric fields read as 0.
+func numberField(obj map[string]interface{}, key string) float64 {
+	n, _ := obj[key].(float64)
+	return n
+}
@@ -0,0 +1,368 @@
+package syncer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"panels_user_manager/pkg/clients"
+	"panels_user_manager/pkg/models"
+	"panels_user_manager/pkg/utils"
+)
+
+// DefaultStateFile is where the sync state is kept when no other file is given.
+const DefaultStateFile = "sync_state.json"
+
+// UserState is what was last pushed to PasarGuard for one 3X-UI client.
+type UserState struct {
+	UUID       string `json:"uuid"` // the client's credential: password for trojan and shadowsocks, otherwise its ID
+	Flow       string `json:"flow,omitempty"`
+	Enable     bool   `json:"enable"`
+	TotalGB    int64  `json:"total_gb"`    // bytes, 0 = unlimited
+	ExpiryTime int64  `json:"expiry_time"` // unix seconds, 0 = never, negative = delayed start in milliseconds
+	Protocol   string `json:"protocol"`
+	Reset      int    `json:"reset"` // periodic usage reset in days
+	SyncedAt   string `json:"synced_at"`
+}
+
+// State is persisted between runs so unchanged users are skipped.
+type State struct {
+	LastRun string               `json:"last_run"`
+	Users   map[string]UserState `json:"users"` // keyed by PasarGuard username
+}
+
+// Config controls a sync run.
+type Config struct {
+	StateFile string
+	Interval  time.Duration
+	GroupIDs  []int // assigned to users created by the sync
+}
+
+// Stats counts the outcome of one sync cycle.
+type Stats struct {
+	Added     int
+	Updated   int
+	Unchanged int
+	Failed    int
+}
+
+// Syncer applies changes made on a 3X-UI panel to a PasarGuard panel.
+type Syncer struct {
+	source *clients.ThreeXUIClient
+	target *clients.PasarGuardClient
+	cfg    Config
+	state  *State
+
+	// pgUsers holds the PasarGuard users by username while a cycle runs; it is fetched on the
+	// first update, so their other protocols' proxy_settings are kept.
+	pgUsers map[string]models.PasarGuardUser
+}
+
+// New creates a Syncer and loads the state of previous runs from cfg.StateFile.
+func New(source *clients.ThreeXUIClient, target *clients.PasarGuardClient, cfg Config) (*Syncer, error) {
+	if cfg.StateFile == "" {
+		cfg.StateFile = DefaultStateFile
+	}
+	state, err := LoadState(cfg.StateFile)
+	if err != nil {
+		return nil, err
+	}
+	return &Syncer{source: source, target: target, cfg: cfg, state: state}, nil
+}
+
+// LoadState reads a state file, returning an empty state if it does not exist yet.
+func LoadState(filename string) (*State, error) {
+	state := &State{}
+	if err := readJSON(filename, state); err != nil {
+		return nil, err
+	}
+	if state.Users == nil {
+		state.Users = make(map[string]UserState)
+	}
+	return state, nil
+}
+
+// SaveState writes the sync state to filename.
+func SaveState(filename string, state *State) error {
+	return writeJSON(filename, state)
+}
+
+// readJSON decodes filename into v, leaving v untouched if the file does not exist yet.
+func readJSON(filename string, v interface{}) error {
+	data, err := os.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading state file: %v", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("error parsing state file '%s': %v", filename, err)
+	}
+	return nil
+}
+
+// writeJSON writes v through a temporary file so an interrupted write never corrupts the state.
+func writeJSON(filename string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", " ")
+	if err != nil {
+		return fmt.Errorf("error creating state JSON: %v", err)
+	}
+	tmp := filename + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("error saving state file: %v", err)
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		return fmt.Errorf("error saving state file: %v", err)
+	}
+	return nil
+}
+
+// Run syncs every cfg.Interval until ctx is cancelled. A failed cycle is reported and retried
+// on the next tick rather than stopping the loop.
+func (s *Syncer) Run(ctx context.Context) error {
+	for {
+		stats, err := s.RunOnce()
+		if err != nil {
+			utils.PrintError(fmt.Sprintf("Sync cycle failed: %v", err))
+		} else {
+			PrintStats(stats)
+		}
+		if s.cfg.Interval <= 0 {
+			return err
+		}
+		fmt.Printf(utils.ColorDim+"Next sync at %s (Ctrl+C to stop)\n"+utils.ColorReset, time.Now().Add(s.cfg.Interval).Format("15:04:05"))
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(s.cfg.Interval):
+		}
+	}
+}
+
+// RunOnce performs a single sync cycle: it reads all 3X-UI clients and creates or updates only
+// the PasarGuard users whose UUID, quota, expiry, reset period or enable flag differ from the last pushed state.
+// Both panels are logged into on every cycle so long-running syncs survive token expiry.
+func (s *Syncer) RunOnce() (Stats, error) {
+	var stats Stats
+	if err := s.source.Login(); err != nil {
+		return stats, fmt.Errorf("3X-UI login failed: %v", err)
+	}
+	if err := s.target.Login(); err != nil {
+		return stats, fmt.Errorf("PasarGuard login failed: %v", err)
+	}
+	inbounds, err := s.source.GetAllInbounds()
+	if err != nil {
+		return stats, fmt.Errorf("error fetching inbounds: %v", err)
+	}
+
+	fmt.Printf("\n"+utils.ColorBrightCyan+"🔄 Sync started at %s"+utils.ColorReset+"\n", time.Now().Format("2006-01-02 15:04:05"))
+	seen := make(map[string]bool)
+	s.pgUsers = nil
+	for _, inbound := range inbounds {
+		var settings models.InboundSettings
+		if strings.TrimSpace(inbound.Settings) == "" || json.Unmarshal([]byte(inbound.Settings), &settings) != nil {
+			continue
+		}
+		var ssSettings struct {
+			Method string `json:"method"`
+		}
+		json.Unmarshal([]byte(inbound.Settings), &ssSettings)
+		for _, client := range settings.Clients {
+			if client.Email == "" {
+				continue
+			}
+			username := sanitizeUsername(client.Email)
+			if seen[username] {
+				utils.VerboseLog("Skipping duplicate client %s in inbound %s", client.Email, inbound.Remark)
+				continue
+			}
+			seen[username] = true
+
+			want := UserState{
+				UUID:       clientCredential(inbound.Protocol, client),
+				Flow:       client.Flow,
+				Enable:     client.Enable,
+				TotalGB:    normalizeQuota(client.TotalGB),
+				ExpiryTime: normalizeExpiry(client.ExpiryTime),
+				Protocol:   inbound.Protocol,
+				Reset:      client.Reset,
+			}
+			prev, known := s.state.Users[username]
+			if known && prev.UUID == want.UUID && prev.Flow == want.Flow && prev.Enable == want.Enable && prev.TotalGB == want.TotalGB && prev.ExpiryTime == want.ExpiryTime && prev.Protocol == want.Protocol && prev.Reset == want.Reset {
+				stats.Unchanged++
+				continue
+			}
+
+			user := models.PasarGuardUser{
+				Username:    username,
+				Email:       client.Email,
+				UUID:        want.UUID,
+				Enable:      want.Enable,
+				TotalGB:     want.TotalGB,
+				ExpiryTime:  want.ExpiryTime,
+				LimitIP:     client.LimitIP,
+				UsedTraffic: -1, // leave PasarGuard's own usage untouched
+				Protocol:    inbound.Protocol,
+				Port:        inbound.Port,
+				Remark:      inbound.Remark,
+
+				ProxySettings: proxySettings(inbound.Protocol, client, ssSettings.Method),
+
+				DataLimitResetStrategy: models.ResetStrategyFromDays(client.Reset),
+			}
+			if err := s.apply(user, known, &stats); err != nil {
+				stats.Failed++
+				fmt.Printf(utils.ColorRed+"  ✗ %s: %v"+utils.ColorReset+"\n", username, err)
+				continue
+			}
+			want.SyncedAt = time.Now().Format(time.RFC3339)
+			s.state.Users[username] = want
+		}
+	}
+
+	// Clients removed from 3X-UI are forgotten, not deleted, so they sync again if re-created.
+	for username := range s.state.Users {
+		if !seen[username] {
+			delete(s.state.Users, username)
+		}
+	}
+	s.state.LastRun = time.Now().Format(time.RFC3339)
+	return stats, SaveState(s.cfg.StateFile, s.state)
+}
+
+// apply updates a known user, or creates a new one. Either path falls back to the other,
+// since the user may have been created or deleted on PasarGuard outside the sync.
+func (s *Syncer) apply(user models.PasarGuardUser, known bool, stats *Stats) error {
+	if known {
+		err := s.update(user, stats)
+		if err == nil {
+			return nil
+		}
+		utils.VerboseLog("Update of %s failed, trying to create it: %v", user.Username, err)
+		if createErr := s.create(user, stats); createErr != nil {
+			return fmt.Errorf("update: %v; create: %v", err, createErr)
+		}
+		return nil
+	}
+	if err := s.create(user, stats); err != nil {
+		if updErr := s.update(user, stats); updErr != nil {
+			return fmt.Errorf("create: %v; update: %v", err, updErr)
+		}
+	}
+	return nil
+}
+
+// update changes an existing user. The synced protocol's credentials are merged into the
+// user's proxy_settings, so credentials of its other protocols are kept.
+func (s *Syncer) update(user models.PasarGuardUser, stats *Stats) error {
+	if s.pgUsers == nil {
+		users, err := s.target.GetAllUsers()
+		if err != nil {
+			return fmt.Errorf("error fetching PasarGuard users: %v", err)
+		}
+		s.pgUsers = make(map[string]models.PasarGuardUser, len(users))
+		for _, u := range users {
+			s.pgUsers[strings.ToLower(u.Username)] = u
+		}
+	}
+	if existing, ok := s.pgUsers[user.Username]; ok {
+		merged := make(map[string]interface{}, len(existing.ProxySettings)+len(user.ProxySettings))
+		for protocol, settings := range existing.ProxySettings {
+			merged[protocol] = settings
+		}
+		for protocol, settings := range user.ProxySettings {
+			merged[protocol] = settings
+		}
+		user.ProxySettings = merged
+	}
+	if err := s.target.UpdateUserByIdentifier(user.Username, user); err != nil {
+		return err
+	}
+	stats.Updated++
+	fmt.Printf(utils.ColorYellow+"  ~ %s updated"+utils.ColorReset+"\n", user.Username)
+	return nil
+}
+
+// create adds a user, carrying over the traffic already consumed on 3X-UI.
+func (s *Syncer) create(user models.PasarGuardUser, stats *Stats) error {
+	if traffic, err := s.source.GetClientTraffic(user.Email); err == nil && traffic != nil {
+		user.UsedTraffic = traffic.Up + traffic.Down
+	}
+	user.GroupIDs = s.cfg.GroupIDs
+	if err := s.target.AddUser(user); err != nil {
+		return err
+	}
+	stats.Added++
+	fmt.Printf(utils.ColorGreen+"  + %s added"+utils.ColorReset+"\n", user.Username)
+	return nil
+}
+
+// clientCredential returns what identifies a client on PasarGuard: the password for trojan and
+// shadowsocks, the ID for every other protocol, as the users export does.
+func clientCredential(protocol string, client models.ClientSetting) string {
+	if protocol == "trojan" || protocol == "shadowsocks" {
+		return client.Password
+	}
+	return client.ID
+}
+
+// proxySettings returns the PasarGuard proxy_settings of a 3X-UI client. inboundMethod is the
+// inbound's shadowsocks cipher, used when the client has none of its own.
+func proxySettings(protocol string, client models.ClientSetting, inboundMethod string) map[string]interface{} {
+	credential := clientCredential(protocol, client)
+	if credential == "" {
+		return map[string]interface{}{}
+	}
+	switch protocol {
+	case "vmess":
+		return map[string]interface{}{"vmess": map[string]interface{}{"id": credential}}
+	case "vless":
+		return map[string]interface{}{"vless": map[string]interface{}{"id": credential, "flow": client.Flow}}
+	case "trojan":
+		return map[string]interface{}{"trojan": map[string]interface{}{"password": credential}}
+	case "shadowsocks":
+		method := client.Method
+		if method == "" {
+			method = inboundMethod
+		}
+		return map[string]interface{}{"shadowsocks": map[string]interface{}{"password": credential, "method": method}}
+	}
+	return map[string]interface{}{}
+}
+
+// PrintStats prints the outcome of one sync cycle.
+func PrintStats(stats Stats) {
+	fmt.Printf(utils.ColorBrightGreen+"✓ Sync finished: %d added, %d updated, %d unchanged"+utils.ColorReset, stats.Added, stats.Updated, stats.Unchanged)
+	if stats.Failed > 0 {
+		fmt.Printf(utils.ColorRed+", %d failed"+utils.ColorReset, stats.Failed)
+	}
+	fmt.Println()
+}
+
+// sanitizeUsername applies the same username rules as the PasarGuard importer.
+func sanitizeUsername(email string) string {
+	return strings.TrimSpace(strings.ReplaceAll(strings.ToLower(email), " ", "_"))
+}
+
+func normalizeQuota(quota int64) int64 {
+	if quota < 0 {
+		return 0
+	}
+	return quota
+}
+
+// normalizeExpiry converts 3X-UI millisecond timestamps to seconds. Negative values are delayed
+// starts and are kept as-is (milliseconds); PasarGuardClient maps them to on_hold.
+func normalizeExpiry(expiry int64) int64 {
+	if expiry <= 0 {
+		return expiry
+	}
+	if expiry > 1e11 {
+		return expiry / 1000
+	}
+	return expiry
+}
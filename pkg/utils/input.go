@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Stdin is the only reader on standard input. Every prompt reads through it: a second buffered
+// reader would read ahead and swallow the answers to later prompts when input is piped.
+var Stdin = bufio.NewReader(os.Stdin)
+
+// ReadLine reads one line from Stdin without its line ending.
+func ReadLine() string {
+	input, _ := Stdin.ReadString('\n')
+	return strings.TrimSpace(input)
+}
+
+// ReadSecret reads one line without echoing it when stdin is a terminal. Piped input is read
+// from Stdin like any other answer.
+func ReadSecret() string {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return ReadLine()
+	}
+	secret, _ := term.ReadPassword(fd)
+	fmt.Println()
+	return strings.TrimSpace(string(secret))
+}